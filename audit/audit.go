@@ -0,0 +1,230 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// Entry records a single page or cancel.
+type Entry struct {
+	Time         time.Time       `json:"time"`
+	RequestID    string          `json:"request_id,omitempty"`
+	Caller       string          `json:"caller,omitempty"`
+	RemoteAddr   string          `json:"remote_addr,omitempty"`
+	Certificate  string          `json:"certificate,omitempty"`
+	Source       string          `json:"source"`
+	Action       string          `json:"action"`
+	Profile      string          `json:"profile"`
+	Target       string          `json:"target"`
+	Colors       []string        `json:"colors,omitempty"`
+	DurationMS   int             `json:"duration_ms,omitempty"`
+	ElapsedMS    int64           `json:"elapsed_ms"`
+	BridgeStatus int             `json:"bridge_status,omitempty"`
+	BridgeErrors []string        `json:"bridge_errors,omitempty"`
+	Outcome      string          `json:"outcome"`
+	Code         types.ErrorCode `json:"code,omitempty"`
+	Message      string          `json:"message,omitempty"`
+}
+
+// Filter selects entries from the audit log. Zero values match every
+// entry.
+type Filter struct {
+	// Since and Until bound the entry time, Since inclusive and Until
+	// exclusive.
+	Since  time.Time
+	Until  time.Time
+	Caller string
+	// Limit keeps only the most recent entries, if set.
+	Limit int
+}
+
+// Matches reports whether the entry passes the filter.
+func (f Filter) Matches(entry Entry) bool {
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return f.Caller == "" || entry.Caller == f.Caller
+}
+
+// Log is an append-only audit log of JSON lines. Once the file grows past
+// MaxBytes it is rotated to <path>.1, shifting older files up to
+// <path>.<MaxFiles> and dropping anything beyond that.
+type Log struct {
+	Path     string
+	MaxBytes int64
+	MaxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens the audit log at path for appending, creating it if needed.
+func Open(path string, maxBytes int64, maxFiles int) (*Log, error) {
+	l := &Log{Path: path, MaxBytes: maxBytes, MaxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Record appends the entry to the log, rotating it first if the entry
+// would push it past MaxBytes.
+func (l *Log) Record(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return os.ErrClosed
+	}
+	var rotateErr error
+	if l.MaxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.MaxBytes {
+		if rotateErr = l.rotate(); rotateErr != nil {
+			rotateErr = fmt.Errorf("failed to rotate audit log: %w", rotateErr)
+			// keep appending to whatever file is at Path rather than
+			// losing entries until the next rotation succeeds
+			if l.file == nil {
+				if err := l.open(); err != nil {
+					return errors.Join(rotateErr, err)
+				}
+			}
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return errors.Join(rotateErr, err)
+}
+
+func (l *Log) rotate() error {
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return err
+	}
+
+	if l.MaxFiles <= 0 {
+		if err := os.Remove(l.Path); err != nil {
+			return err
+		}
+		return l.open()
+	}
+
+	for i := l.MaxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(l.rotated(i), l.rotated(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(l.Path, l.rotated(1)); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+func (l *Log) rotated(n int) string {
+	return fmt.Sprintf("%s.%d", l.Path, n)
+}
+
+// Query returns the entries matching the filter, oldest first, reading
+// through the rotated files as well. With a Limit, only that many entries
+// are held on to while reading. Lines that fail to decode are skipped. The
+// files are read without holding up Record, so a rotation during the query
+// can make it miss or repeat entries.
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	l.mu.Lock()
+	paths := []string{}
+	for i := l.MaxFiles; i >= 1; i-- {
+		paths = append(paths, l.rotated(i))
+	}
+	paths = append(paths, l.Path)
+	l.mu.Unlock()
+
+	entries := []Entry{}
+	for _, path := range paths {
+		var err error
+		if entries, err = readEntries(path, filter, entries); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// readEntries appends the entries of the file at path matching the filter
+// to entries, dropping the oldest ones whenever there are twice as many as
+// the filter's Limit.
+func readEntries(path string, filter Filter, entries []Entry) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if !filter.Matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) >= 2*filter.Limit {
+			entries = append([]Entry(nil), entries[len(entries)-filter.Limit:]...)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Close closes the log file. Records after Close fail.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
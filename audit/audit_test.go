@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog_RecordAndQuery(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	assert.NoError(t, err)
+	defer log.Close()
+
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	for i, caller := range []string{"ci", "ops", "ci", "ops"} {
+		err := log.Record(Entry{Time: start.Add(time.Duration(i) * time.Hour), Caller: caller, Action: "page", Outcome: "okay"})
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		description string
+		filter      Filter
+		expected    []int
+	}{
+		{"everything", Filter{}, []int{0, 1, 2, 3}},
+		{"caller", Filter{Caller: "ci"}, []int{0, 2}},
+		{"since is inclusive", Filter{Since: start.Add(time.Hour)}, []int{1, 2, 3}},
+		{"until is exclusive", Filter{Until: start.Add(2 * time.Hour)}, []int{0, 1}},
+		{"time range and caller", Filter{Since: start.Add(time.Hour), Until: start.Add(4 * time.Hour), Caller: "ops"}, []int{1, 3}},
+		{"limit keeps the latest", Filter{Limit: 2}, []int{2, 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			entries, err := log.Query(test.filter)
+			assert.NoError(t, err)
+
+			var hours []int
+			for _, entry := range entries {
+				hours = append(hours, int(entry.Time.Sub(start).Hours()))
+			}
+			assert.Equal(t, test.expected, hours)
+		})
+	}
+}
+
+func TestLog_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path, 200, 2)
+	assert.NoError(t, err)
+	defer log.Close()
+
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, log.Record(Entry{Time: start.Add(time.Duration(i) * time.Minute), Source: "PageHandler", Outcome: "okay"}))
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(200))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	entries, err := log.Query(Filter{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	assert.Less(t, len(entries), 10)
+	for i := 1; i < len(entries); i++ {
+		assert.True(t, entries[i-1].Time.Before(entries[i].Time))
+	}
+	assert.Equal(t, start.Add(9*time.Minute), entries[len(entries)-1].Time)
+}
+
+func TestLog_ReopenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	log, err := Open(path, 0, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, log.Record(Entry{Source: "CLI"}))
+	assert.NoError(t, log.Close())
+	assert.Error(t, log.Record(Entry{Source: "CLI"}))
+
+	log, err = Open(path, 0, 0)
+	assert.NoError(t, err)
+	defer log.Close()
+	assert.NoError(t, log.Record(Entry{Source: "MQTT"}))
+
+	entries, err := log.Query(Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestLog_RotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path, 100, 1)
+	assert.NoError(t, err)
+	defer log.Close()
+
+	// a non-empty directory in the way of <path>.1 makes the rotation fail
+	assert.NoError(t, os.MkdirAll(filepath.Join(path+".1", "blocker"), 0o700))
+
+	entry := Entry{Source: "PageHandler", Message: strings.Repeat("a", 60)}
+	assert.NoError(t, log.Record(entry))
+	assert.ErrorContains(t, log.Record(entry), "failed to rotate audit log")
+	assert.ErrorContains(t, log.Record(entry), "failed to rotate audit log")
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, strings.Count(string(contents), "\n"))
+}
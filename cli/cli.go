@@ -0,0 +1,241 @@
+// Package cli implements the huproxy client subcommands, which call a
+// running huproxy or talk to the bridge directly.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/client"
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// ErrFailed is returned once a failed outcome has been printed, so callers
+// only need to set the exit code.
+var ErrFailed = errors.New("request failed")
+
+const usage = `Usage: huproxy [command] [flags]
+
+Commands:
+  serve      run the huproxy server (the default)
+  page       page a profile
+  cancel     cancel a page on a profile
+  ping       check that huproxy or the bridge is configured and reachable
+  resources  list the rooms and zones on the bridge
+
+Run huproxy <command> -h for the flags of a command.
+`
+
+// CLI runs the client subcommands.
+type CLI struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	// Handler builds a Handler from the server config, used by --direct
+	// and resources to talk to the bridge without a running huproxy.
+	Handler func() (*handlers.Handler, error)
+}
+
+type options struct {
+	url     string
+	apiKey  string
+	direct  bool
+	json    bool
+	profile string
+	target  string
+	timeout time.Duration
+}
+
+// Run runs the subcommand named by args[0] with the rest of args as flags.
+func (c *CLI) Run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(c.Stderr, usage)
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "page", "cancel":
+		opts, err := c.parse(args, true)
+		if err != nil {
+			return err
+		}
+		return c.runAction(args[0], opts)
+	case "ping":
+		opts, err := c.parse(args, false)
+		if err != nil {
+			return err
+		}
+		return c.runPing(opts)
+	case "resources":
+		opts, err := c.parse(args, false)
+		if err != nil {
+			return err
+		}
+		return c.runResources(opts)
+	case "help", "-h", "--help":
+		fmt.Fprint(c.Stdout, usage)
+		return nil
+	default:
+		fmt.Fprintf(c.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		return flag.ErrHelp
+	}
+}
+
+// parse reads the flags of a subcommand. Commands that take a profile also
+// accept it as their only argument.
+func (c *CLI) parse(args []string, takesProfile bool) (options, error) {
+	defaultURL := os.Getenv("HUPROXY_URL")
+	if defaultURL == "" {
+		defaultURL = "http://localhost:9090"
+	}
+
+	var opts options
+	fs := flag.NewFlagSet("huproxy "+args[0], flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	fs.StringVar(&opts.url, "url", defaultURL, "huproxy to call, defaults to $HUPROXY_URL")
+	fs.StringVar(&opts.apiKey, "api-key", os.Getenv("HUPROXY_API_KEY"), "API key to call huproxy with, defaults to $HUPROXY_API_KEY")
+	fs.BoolVar(&opts.direct, "direct", false, "talk to the bridge directly using the server config instead of calling huproxy")
+	fs.BoolVar(&opts.json, "json", false, "print JSON instead of text")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "how long to wait for huproxy or the bridge")
+	if takesProfile {
+		fs.StringVar(&opts.profile, "profile", "", "page profile to use, defaults to the default profile")
+		fs.StringVar(&opts.target, "target", "", "grouped light ID to use instead of the profile's, needs --direct")
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return opts, err
+	}
+	if takesProfile && opts.profile == "" && fs.NArg() == 1 {
+		opts.profile = fs.Arg(0)
+	} else if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if opts.target != "" && !opts.direct {
+		return opts, errors.New("--target needs --direct")
+	}
+	return opts, nil
+}
+
+func (c *CLI) runAction(name string, opts options) error {
+	if opts.direct {
+		h, err := c.Handler()
+		if err != nil {
+			return err
+		}
+		if h.Audit != nil {
+			defer h.Audit.Close()
+		}
+		action := handlers.ActionPage
+		if name == "cancel" {
+			action = handlers.ActionCancel
+		}
+		return c.printResponse(opts, h.RunCommands("CLI", []handlers.Command{{Action: action, Profile: opts.profile, Target: opts.target}}))
+	}
+
+	hc, err := client.New(opts.url, client.WithAPIKey(opts.apiKey))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	if name == "cancel" {
+		err = hc.Cancel(ctx, client.CancelRequest{Profile: opts.profile})
+	} else {
+		err = hc.Page(ctx, client.PageRequest{Profile: opts.profile})
+	}
+	return c.printResponse(opts, responseFromError(err))
+}
+
+func (c *CLI) runPing(opts options) error {
+	if opts.direct {
+		h, err := c.Handler()
+		if err != nil {
+			return err
+		}
+		response := h.Ping("CLI")
+		if response.Status == types.Success().Status {
+			if err := h.CheckBridge(); err != nil {
+				response = handlers.BridgeFailure(err)
+			}
+		}
+		return c.printResponse(opts, response)
+	}
+
+	hc, err := client.New(opts.url, client.WithAPIKey(opts.apiKey))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	return c.printResponse(opts, responseFromError(hc.Ping(ctx)))
+}
+
+func (c *CLI) runResources(opts options) error {
+	h, err := c.Handler()
+	if err != nil {
+		return err
+	}
+	groups, err := h.Groups()
+	if err != nil {
+		return c.printResponse(opts, handlers.BridgeFailure(err))
+	}
+
+	if opts.json {
+		if groups == nil {
+			groups = []handlers.Group{}
+		}
+		return json.NewEncoder(c.Stdout).Encode(groups)
+	}
+
+	w := tabwriter.NewWriter(c.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tNAME\tID\tGROUPED LIGHT ID")
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", group.Type, group.Name, group.ID, group.GroupedLightID)
+	}
+	return w.Flush()
+}
+
+// printResponse prints the response and returns ErrFailed if it is broke.
+func (c *CLI) printResponse(opts options, response types.Response) error {
+	if opts.json {
+		json.NewEncoder(c.Stdout).Encode(response)
+	} else if response.Code != "" {
+		fmt.Fprintf(c.Stdout, "%s (%s): %s\n", response.Status, response.Code, response.Message)
+	} else if response.Message != "" {
+		fmt.Fprintf(c.Stdout, "%s: %s\n", response.Status, response.Message)
+	} else {
+		fmt.Fprintln(c.Stdout, response.Status)
+	}
+
+	if response.Status != types.Success().Status {
+		return ErrFailed
+	}
+	return nil
+}
+
+func responseFromError(err error) types.Response {
+	var apiErr *client.Error
+	switch {
+	case err == nil:
+		return types.Success()
+	case errors.As(err, &apiErr):
+		return types.Response{
+			Status:    types.Error("").Status,
+			Code:      apiErr.Code,
+			Message:   apiErr.Message,
+			RequestID: apiErr.RequestID,
+			Details:   apiErr.Details,
+		}
+	default:
+		return types.Error(err.Error())
+	}
+}
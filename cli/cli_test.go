@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopNotifier struct{}
+
+func (nopNotifier) SendErrorNotification(message string) error                  { return nil }
+func (nopNotifier) SendNotification(level types.LogLevel, message string) error { return nil }
+
+// newDirectCLI returns a CLI whose direct handler talks to bridge.
+func newDirectCLI(bridge *httptest.Server, stdout io.Writer) *CLI {
+	return &CLI{
+		Stdout: stdout,
+		Stderr: io.Discard,
+		Handler: func() (*handlers.Handler, error) {
+			log := logrus.New()
+			log.SetOutput(io.Discard)
+			cfg := &types.Config{
+				BridgeAddress:  strings.TrimPrefix(bridge.URL, "https://"),
+				GroupedLightID: "group1",
+				HueUsername:    "user123",
+				Profiles: map[string]types.PageProfile{
+					types.DefaultProfile: {Name: types.DefaultProfile, GroupedLightID: "group1", DurationMS: 15000},
+				},
+			}
+			return handlers.NewHandler(cfg, log, nopNotifier{}), nil
+		},
+	}
+}
+
+func TestCLI_Page(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/page", r.URL.Path)
+		if r.URL.Query().Get("profile") == "critical" {
+			json.NewEncoder(w).Encode(types.Success())
+		} else {
+			json.NewEncoder(w).Encode(types.Error("unknown profile"))
+		}
+	}))
+	defer server.Close()
+
+	var stdout bytes.Buffer
+	c := &CLI{Stdout: &stdout, Stderr: io.Discard}
+
+	assert.NoError(t, c.Run([]string{"page", "--url", server.URL, "critical"}))
+	assert.Equal(t, "okay\n", stdout.String())
+
+	stdout.Reset()
+	assert.ErrorIs(t, c.Run([]string{"page", "--url", server.URL, "--json", "--profile", "nope"}), ErrFailed)
+	assert.JSONEq(t, `{"status":"broke","message":"unknown profile"}`, stdout.String())
+
+	assert.Error(t, c.Run([]string{"page", "--url", server.URL, "--target", "office"}))
+}
+
+func TestCLI_DirectCancel(t *testing.T) {
+	var paths []string
+	bridge := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer bridge.Close()
+
+	var stdout bytes.Buffer
+	assert.NoError(t, newDirectCLI(bridge, &stdout).Run([]string{"cancel", "--direct", "--target", "office"}))
+	assert.Equal(t, "okay\n", stdout.String())
+	assert.Equal(t, []string{"/clip/v2/resource/grouped_light/office"}, paths)
+}
+
+func TestCLI_Resources(t *testing.T) {
+	bridge := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "user123", r.Header.Get("hue-application-key"))
+		switch r.URL.Path {
+		case "/clip/v2/resource/room":
+			w.Write([]byte(`{"errors":[],"data":[{"id":"room1","type":"room","metadata":{"name":"Office"},"services":[{"rid":"office","rtype":"grouped_light"}]}]}`))
+		case "/clip/v2/resource/zone":
+			w.Write([]byte(`{"errors":[],"data":[{"id":"zone1","type":"zone","metadata":{"name":"Desk"},"services":[{"rid":"desk","rtype":"grouped_light"}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer bridge.Close()
+
+	var stdout bytes.Buffer
+	assert.NoError(t, newDirectCLI(bridge, &stdout).Run([]string{"resources", "--json"}))
+
+	var groups []handlers.Group
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &groups))
+	assert.Equal(t, []handlers.Group{
+		{ID: "room1", Name: "Office", Type: "room", GroupedLightID: "office"},
+		{ID: "zone1", Name: "Desk", Type: "zone", GroupedLightID: "desk"},
+	}, groups)
+
+	stdout.Reset()
+	assert.NoError(t, newDirectCLI(bridge, &stdout).Run([]string{"resources"}))
+	assert.Contains(t, stdout.String(), "room  Office  room1  office")
+}
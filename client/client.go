@@ -0,0 +1,234 @@
+// Package client is a Go client for the huproxy HTTP API.
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/signing"
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// ErrUnexpectedResponse is returned when huproxy answers with something
+// other than a types.Response.
+var ErrUnexpectedResponse = errors.New("unexpected response from huproxy")
+
+// Error is returned when huproxy answers with a "broke" status.
+type Error struct {
+	StatusCode int
+	Code       types.ErrorCode
+	Message    string
+	RequestID  string
+	Details    *types.ErrorDetails
+}
+
+// Retryable reports whether the request may succeed if it is retried
+// later.
+func (e *Error) Retryable() bool {
+	if e.Code != "" {
+		return e.Code.Retryable()
+	}
+	return e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return "huproxy request failed"
+	}
+	return "huproxy request failed: " + e.Message
+}
+
+// PageRequest asks huproxy to page a profile. An empty profile pages the
+// default profile.
+type PageRequest struct {
+	Profile string
+}
+
+// CancelRequest asks huproxy to cancel a page on a profile's lights. An
+// empty profile cancels on the default profile.
+type CancelRequest struct {
+	Profile string
+}
+
+// Client calls a running huproxy. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiKey     string
+	signingKey string
+	secret     string
+	retries    int
+	retryWait  time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey sends key as a bearer token with every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithSigningKey signs every request with the secret of the named API
+// key instead of sending a key as is.
+func WithSigningKey(name, secret string) Option {
+	return func(c *Client) {
+		c.signingKey = name
+		c.secret = secret
+	}
+}
+
+// WithRetries retries requests that fail to connect or get a retryable
+// error up to retries times, waiting wait between attempts.
+func WithRetries(retries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryWait = wait
+	}
+}
+
+// New creates a Client for the huproxy at baseURL, e.g. http://huproxy:9090.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid huproxy URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("invalid huproxy URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Ping checks that huproxy is up and has its bridge settings configured.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/ping", nil)
+}
+
+// Page pages the requested profile.
+func (c *Client) Page(ctx context.Context, request PageRequest) error {
+	return c.do(ctx, http.MethodPost, "/page", profileQuery(request.Profile))
+}
+
+// Cancel cancels any page running on the requested profile's lights.
+func (c *Client) Cancel(ctx context.Context, request CancelRequest) error {
+	return c.do(ctx, http.MethodPost, "/cancel", profileQuery(request.Profile))
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = c.attempt(ctx, method, path, query)
+		if !retry || attempt >= c.retries {
+			return err
+		}
+
+		select {
+		case <-time.After(c.retryWait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt makes a single request, reporting whether a failure is worth
+// retrying.
+func (c *Client) attempt(ctx context.Context, method, path string, query url.Values) (bool, error) {
+	endpoint := *c.baseURL
+	endpoint.Path += path
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.signingKey != "" {
+		if err := c.sign(req); err != nil {
+			return false, err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retry, err
+	}
+
+	var response types.Response
+	if err := json.Unmarshal(body, &response); err != nil || response.Status == "" {
+		return retry, fmt.Errorf("%w: %s", ErrUnexpectedResponse, resp.Status)
+	}
+	if response.Status != types.Success().Status {
+		apiErr := &Error{
+			StatusCode: resp.StatusCode,
+			Code:       response.Code,
+			Message:    response.Message,
+			RequestID:  response.RequestID,
+			Details:    response.Details,
+		}
+		return apiErr.Retryable(), apiErr
+	}
+	return false, nil
+}
+
+// sign adds the X-Huproxy-Signature header to a bodyless request.
+func (c *Client) sign(req *http.Request) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(c.secret))
+	mac.Write(signing.Payload(timestamp, hex.EncodeToString(nonce), req.Method, req.URL.RequestURI(), nil))
+	req.Header.Set(signing.Header, fmt.Sprintf("key=%s,t=%s,nonce=%s,v1=%s", c.signingKey, timestamp, hex.EncodeToString(nonce), hex.EncodeToString(mac.Sum(nil))))
+	return nil
+}
+
+func profileQuery(profile string) url.Values {
+	query := url.Values{}
+	if profile != "" {
+		query.Set("profile", profile)
+	}
+	return query
+}
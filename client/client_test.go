@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Page(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/page", r.URL.Path)
+		assert.Equal(t, "critical", r.URL.Query().Get("profile"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(types.Success())
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL+"/", WithAPIKey("secret"))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Page(context.Background(), PageRequest{Profile: "critical"}))
+}
+
+func TestClient_SigningKey(t *testing.T) {
+	var signed bool
+	handler := handlers.NewHandler(&types.Config{
+		APIKeys:             []types.APIKey{{Caller: types.Caller{Name: "cron"}, Key: "secret", Auth: types.AuthSignature}},
+		SignatureMaxAgeSecs: 300,
+	}, logrus.New(), nil)
+	server := httptest.NewServer(handler.VerifySignature(func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := handlers.CallerFromContext(r.Context())
+		signed = ok && caller.Name == "cron"
+		json.NewEncoder(w).Encode(types.Success())
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, WithSigningKey("cron", "secret"))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Cancel(context.Background(), CancelRequest{Profile: "critical"}))
+	assert.True(t, signed)
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		description string
+		statusCode  int
+		body        string
+		expectedErr error
+	}{
+		{"broke with message", http.StatusOK, `{"status":"broke","message":"unknown profile"}`, &Error{StatusCode: http.StatusOK, Message: "unknown profile"}},
+		{
+			"structured error",
+			http.StatusBadGateway,
+			`{"status":"broke","code":"bridge_rejected","message":"the Hue Bridge rejected the request","details":{"bridge_status":403,"bridge_errors":["unauthorized user"]}}`,
+			&Error{
+				StatusCode: http.StatusBadGateway,
+				Code:       types.ErrorBridgeRejected,
+				Message:    "the Hue Bridge rejected the request",
+				Details:    &types.ErrorDetails{BridgeStatus: 403, BridgeErrors: []string{"unauthorized user"}},
+			},
+		},
+		{"not a response", http.StatusNotFound, `404 page not found`, ErrUnexpectedResponse},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.statusCode)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			c, err := New(server.URL)
+			assert.NoError(t, err)
+
+			err = c.Cancel(context.Background(), CancelRequest{})
+			var apiErr *Error
+			if errors.As(test.expectedErr, &apiErr) {
+				assert.Equal(t, test.expectedErr, err)
+			} else {
+				assert.ErrorIs(t, err, test.expectedErr)
+			}
+		})
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	var calls atomic.Int32
+	failure := types.Failure(types.ErrorBridgeTimeout, "the Hue Bridge did not answer in time")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(failure.StatusCode)
+			json.NewEncoder(w).Encode(failure)
+			return
+		}
+		json.NewEncoder(w).Encode(types.Success())
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, WithRetries(2, 0))
+	assert.NoError(t, err)
+	assert.NoError(t, c.Ping(context.Background()))
+	assert.Equal(t, int32(3), calls.Load())
+
+	calls.Store(0)
+	c, err = New(server.URL, WithRetries(1, 0))
+	assert.NoError(t, err)
+	var apiErr *Error
+	assert.ErrorAs(t, c.Ping(context.Background()), &apiErr)
+	assert.Equal(t, http.StatusGatewayTimeout, apiErr.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// a rejected request fails the same way every time
+	calls.Store(0)
+	failure = types.Failure(types.ErrorBridgeRejected, "the Hue Bridge rejected the request")
+	assert.ErrorAs(t, c.Ping(context.Background()), &apiErr)
+	assert.Equal(t, types.ErrorBridgeRejected, apiErr.Code)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestNew_InvalidURL(t *testing.T) {
+	_, err := New("huproxy:9090")
+	assert.Error(t, err)
+}
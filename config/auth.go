@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loadAPIKeys reads the API keys listed in API_KEYS, each configured
+// through API_KEY_<NAME>, API_KEY_<NAME>_PROFILES, API_KEY_<NAME>_TARGETS
+// and API_KEY_<NAME>_AUTH.
+func loadAPIKeys(log *logrus.Logger) []types.APIKey {
+	var keys []types.APIKey
+
+	for _, name := range splitList(os.Getenv("API_KEYS")) {
+		prefix := "API_KEY_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		key := types.APIKey{
+			Caller: types.Caller{
+				Name:     name,
+				Profiles: splitList(os.Getenv(prefix + "_PROFILES")),
+				Targets:  splitList(os.Getenv(prefix + "_TARGETS")),
+			},
+			Key:  os.Getenv(prefix),
+			Auth: strings.ToLower(os.Getenv(prefix + "_AUTH")),
+		}
+		if key.Key == "" {
+			log.Warnf("Ignoring API key %s, %s is not set.", name, prefix)
+			continue
+		}
+		if key.Auth == "" {
+			key.Auth = types.AuthAny
+		}
+		if !slices.Contains([]string{types.AuthAny, types.AuthBearer, types.AuthSignature}, key.Auth) {
+			log.Warnf("Ignoring API key %s, %s_AUTH must be any, bearer or signature.", name, prefix)
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// loadTLSClients reads the client certificate identities listed in
+// TLS_CLIENTS, each configured through TLS_CLIENT_<NAME>_NAMES,
+// TLS_CLIENT_<NAME>_PROFILES and TLS_CLIENT_<NAME>_TARGETS.
+func loadTLSClients(log *logrus.Logger) []types.TLSClient {
+	var clients []types.TLSClient
+
+	for _, name := range splitList(os.Getenv("TLS_CLIENTS")) {
+		prefix := "TLS_CLIENT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		client := types.TLSClient{
+			Caller: types.Caller{
+				Name:     name,
+				Profiles: splitList(os.Getenv(prefix + "_PROFILES")),
+				Targets:  splitList(os.Getenv(prefix + "_TARGETS")),
+			},
+			Names: splitList(os.Getenv(prefix + "_NAMES")),
+		}
+		if len(client.Names) == 0 {
+			log.Warnf("Ignoring TLS client %s, %s_NAMES is not set.", name, prefix)
+			continue
+		}
+
+		clients = append(clients, client)
+	}
+
+	return clients
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadAPIKeys(t *testing.T) {
+	var logOutput []string
+	log := logrus.New()
+	log.SetOutput(&logWriter{logs: &logOutput})
+
+	env := map[string]string{
+		"API_KEYS":                       "ci, home-assistant, missing, broken",
+		"API_KEY_CI":                     "secret1",
+		"API_KEY_CI_PROFILES":            "default,deploy",
+		"API_KEY_HOME_ASSISTANT":         "secret2",
+		"API_KEY_HOME_ASSISTANT_TARGETS": "office",
+		"API_KEY_HOME_ASSISTANT_AUTH":    "Signature",
+		"API_KEY_BROKEN":                 "secret3",
+		"API_KEY_BROKEN_AUTH":            "basic",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	keys := loadAPIKeys(log)
+
+	assert.Equal(t, []types.APIKey{
+		{Caller: types.Caller{Name: "ci", Profiles: []string{"default", "deploy"}}, Key: "secret1", Auth: types.AuthAny},
+		{Caller: types.Caller{Name: "home-assistant", Targets: []string{"office"}}, Key: "secret2", Auth: types.AuthSignature},
+	}, keys)
+	assert.Len(t, logOutput, 2)
+
+	assert.True(t, keys[0].Allows(types.PageProfile{Name: "deploy", GroupedLightID: "office"}))
+	assert.False(t, keys[0].Allows(types.PageProfile{Name: "critical", GroupedLightID: "office"}))
+	assert.False(t, keys[1].Allows(types.PageProfile{Name: "critical", GroupedLightID: "group1"}))
+	assert.False(t, keys[1].Accepts(types.AuthBearer))
+}
+
+func TestLoadTLSClients(t *testing.T) {
+	var logOutput []string
+	log := logrus.New()
+	log.SetOutput(&logWriter{logs: &logOutput})
+
+	env := map[string]string{
+		"TLS_CLIENTS":            "ci,missing",
+		"TLS_CLIENT_CI_NAMES":    "ci.example.com, spiffe://example.com/ci",
+		"TLS_CLIENT_CI_PROFILES": "deploy",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	assert.Equal(t, []types.TLSClient{{
+		Caller: types.Caller{Name: "ci", Profiles: []string{"deploy"}},
+		Names:  []string{"ci.example.com", "spiffe://example.com/ci"},
+	}}, loadTLSClients(log))
+	assert.Len(t, logOutput, 1)
+}
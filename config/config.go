@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/YashdalfTheGray/huproxy/color"
 	"github.com/YashdalfTheGray/huproxy/types"
@@ -10,6 +13,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultWebhookBody is the body template used by the webhook notifier when
+// WEBHOOK_BODY is not set.
+const DefaultWebhookBody = `{"level":{{json .Level.String}},"message":{{json .Message}},"time":{{json .Time}},"handler":{{json .Handler}},"caller":{{json .Caller}},"target":{{json .Target}},"status":{{json .Status}},"request_id":{{json .RequestID}}}`
+
 // LoadConfig reads the environment variables, sets defaults, validates,
 // and returns a Config object.
 func LoadConfig(log *logrus.Logger) (*types.Config, error) {
@@ -20,6 +27,43 @@ func LoadConfig(log *logrus.Logger) (*types.Config, error) {
 		HueUsername:            os.Getenv("HUE_USERNAME"),
 		StartColorHex:          os.Getenv("START_COLOR"),
 		JumpColorHex:           os.Getenv("JUMP_COLOR"),
+		NtfyTopicURL:           os.Getenv("NTFY_TOPIC_URL"),
+		NtfyToken:              os.Getenv("NTFY_TOKEN"),
+		GotifyURL:              os.Getenv("GOTIFY_URL"),
+		GotifyAppToken:         os.Getenv("GOTIFY_APP_TOKEN"),
+		SMTPHost:               os.Getenv("SMTP_HOST"),
+		SMTPUsername:           os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:           os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:               os.Getenv("SMTP_FROM"),
+		SMTPTLSMode:            strings.ToLower(os.Getenv("SMTP_TLS_MODE")),
+		WebhookURL:             os.Getenv("WEBHOOK_URL"),
+		WebhookMethod:          os.Getenv("WEBHOOK_METHOD"),
+		WebhookBody:            os.Getenv("WEBHOOK_BODY"),
+		AlertmanagerProfile:    os.Getenv("ALERTMANAGER_DEFAULT_PROFILE"),
+		GrafanaProfile:         os.Getenv("GRAFANA_DEFAULT_PROFILE"),
+		PagerDutySecret:        os.Getenv("PAGERDUTY_WEBHOOK_SECRET"),
+		PagerDutyProfile:       os.Getenv("PAGERDUTY_PROFILE"),
+		PagerDutyEscalation:    os.Getenv("PAGERDUTY_ESCALATION_PROFILE"),
+		GitHubSecret:           os.Getenv("GITHUB_WEBHOOK_SECRET"),
+		GitHubRepos:            splitList(os.Getenv("GITHUB_REPOS")),
+		GitHubBranches:         splitList(os.Getenv("GITHUB_BRANCHES")),
+		GitHubWorkflows:        splitList(os.Getenv("GITHUB_WORKFLOWS")),
+		GitHubEnvironments:     splitList(os.Getenv("GITHUB_DEPLOY_ENVIRONMENTS")),
+		GitHubFailureProfile:   os.Getenv("GITHUB_FAILURE_PROFILE"),
+		GitHubSuccessProfile:   os.Getenv("GITHUB_SUCCESS_PROFILE"),
+		OpsgenieProfile:        os.Getenv("OPSGENIE_DEFAULT_PROFILE"),
+		MQTTBrokerURL:          os.Getenv("MQTT_BROKER_URL"),
+		MQTTClientID:           os.Getenv("MQTT_CLIENT_ID"),
+		MQTTUsername:           os.Getenv("MQTT_USERNAME"),
+		MQTTPassword:           os.Getenv("MQTT_PASSWORD"),
+		MQTTTopicPrefix:        strings.Trim(os.Getenv("MQTT_TOPIC_PREFIX"), "/"),
+		TLSCertFile:            os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:             os.Getenv("TLS_KEY_FILE"),
+		TLSClientCAFile:        os.Getenv("TLS_CLIENT_CA_FILE"),
+		TLSClientAuth:          strings.ToLower(os.Getenv("TLS_CLIENT_AUTH")),
+		ListenAddress:          os.Getenv("LISTEN_ADDRESS"),
+		GRPCListenAddress:      os.Getenv("GRPC_LISTEN_ADDRESS"),
+		AuditLogFile:           os.Getenv("AUDIT_LOG_FILE"),
 	}
 
 	if config.ErrorDiscordWebhookUrl == "" {
@@ -60,5 +104,301 @@ func LoadConfig(log *logrus.Logger) (*types.Config, error) {
 	}
 	config.DurationMS = durationSeconds * 1000
 
+	dedupStr := os.Getenv("NOTIFICATION_DEDUP_WINDOW_SECONDS")
+	if dedupStr == "" {
+		dedupStr = "300"
+	}
+	dedupSeconds, err := strconv.Atoi(dedupStr)
+	if err != nil || dedupSeconds < 0 {
+		log.Warn("Invalid NOTIFICATION_DEDUP_WINDOW_SECONDS value, using default of 300 seconds.")
+		dedupSeconds = 300
+	}
+	config.DedupWindowSeconds = dedupSeconds
+
+	config.NtfyTags = splitList(os.Getenv("NTFY_TAGS"))
+
+	if priorityStr := os.Getenv("NTFY_PRIORITY"); priorityStr != "" {
+		priority, err := strconv.Atoi(priorityStr)
+		if err != nil || priority < 1 || priority > 5 {
+			log.Warn("Invalid NTFY_PRIORITY value, priority will follow the log level.")
+		} else {
+			config.NtfyPriority = priority
+		}
+	}
+
+	if priorityStr := os.Getenv("GOTIFY_PRIORITY"); priorityStr != "" {
+		priority, err := strconv.Atoi(priorityStr)
+		if err != nil || priority < 1 || priority > 10 {
+			log.Warn("Invalid GOTIFY_PRIORITY value, priority will follow the log level.")
+		} else {
+			config.GotifyPriority = priority
+		}
+	}
+
+	if config.GotifyURL != "" && config.GotifyAppToken == "" {
+		log.Warn("GOTIFY_URL is set without GOTIFY_APP_TOKEN, Gotify notifications will be rejected")
+	}
+
+	config.SMTPTo = splitList(os.Getenv("SMTP_TO"))
+
+	switch config.SMTPTLSMode {
+	case "":
+		config.SMTPTLSMode = "starttls"
+	case "starttls", "tls", "none":
+	default:
+		log.Warn("Invalid SMTP_TLS_MODE value, using default of starttls.")
+		config.SMTPTLSMode = "starttls"
+	}
+
+	portStr := os.Getenv("SMTP_PORT")
+	if portStr == "" {
+		portStr = defaultSMTPPort(config.SMTPTLSMode)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		log.Warn("Invalid SMTP_PORT value, using the default port for the TLS mode.")
+		port, _ = strconv.Atoi(defaultSMTPPort(config.SMTPTLSMode))
+	}
+	config.SMTPPort = port
+
+	// headers are newline separated, accepting a literal \n for env files
+	// that can't hold multi-line values
+	webhookHeaders := strings.ReplaceAll(os.Getenv("WEBHOOK_HEADERS"), `\n`, "\n")
+	for _, header := range strings.Split(webhookHeaders, "\n") {
+		if header = strings.TrimSpace(header); header == "" {
+			continue
+		}
+		if !strings.Contains(header, ":") {
+			log.Warnf("Ignoring WEBHOOK_HEADERS entry without a colon: %s", header)
+			continue
+		}
+		config.WebhookHeaders = append(config.WebhookHeaders, header)
+	}
+	if config.WebhookMethod == "" {
+		config.WebhookMethod = "POST"
+	}
+	if config.WebhookBody == "" {
+		config.WebhookBody = DefaultWebhookBody
+	}
+
+	if config.SMTPHost != "" && (config.SMTPFrom == "" || len(config.SMTPTo) == 0) {
+		log.Warn("SMTP_HOST is set without SMTP_FROM or SMTP_TO, email notifications are disabled")
+	}
+
+	config.Profiles = loadProfiles(config, os.Getenv("PAGE_PROFILES"), log)
+
+	config.AlertmanagerRoutes = loadRoutes("ALERTMANAGER_ROUTES", config.Profiles, log)
+	config.AlertmanagerProfile = fallbackProfile("ALERTMANAGER_DEFAULT_PROFILE", config.AlertmanagerProfile, config.Profiles, log)
+
+	config.GrafanaRoutes = loadRoutes("GRAFANA_ROUTES", config.Profiles, log)
+	config.GrafanaProfile = fallbackProfile("GRAFANA_DEFAULT_PROFILE", config.GrafanaProfile, config.Profiles, log)
+
+	config.PagerDutyProfile = profileOrDefault("PAGERDUTY_PROFILE", config.PagerDutyProfile, types.DefaultProfile, config.Profiles, log)
+	config.PagerDutyEscalation = profileOrDefault("PAGERDUTY_ESCALATION_PROFILE", config.PagerDutyEscalation, config.PagerDutyProfile, config.Profiles, log)
+
+	if len(config.GitHubBranches) == 0 {
+		config.GitHubBranches = []string{"main"}
+	}
+	if len(config.GitHubEnvironments) == 0 {
+		config.GitHubEnvironments = []string{"production"}
+	}
+	config.GitHubFailureProfile = profileOrDefault("GITHUB_FAILURE_PROFILE", config.GitHubFailureProfile, types.DefaultProfile, config.Profiles, log)
+	config.GitHubSuccessProfile = profileOrDefault("GITHUB_SUCCESS_PROFILE", config.GitHubSuccessProfile, "", config.Profiles, log)
+
+	config.OpsgenieRoutes = loadRoutes("OPSGENIE_ROUTES", config.Profiles, log)
+	config.OpsgenieProfile = fallbackProfile("OPSGENIE_DEFAULT_PROFILE", config.OpsgenieProfile, config.Profiles, log)
+
+	config.GenericWebhooks = loadGenericWebhooks(config.Profiles, log)
+
+	if config.MQTTClientID == "" {
+		config.MQTTClientID = "huproxy"
+	}
+	if config.MQTTTopicPrefix == "" {
+		config.MQTTTopicPrefix = "huproxy"
+	}
+	healthStr := os.Getenv("MQTT_HEALTH_INTERVAL_SECONDS")
+	if healthStr == "" {
+		healthStr = "60"
+	}
+	healthSeconds, err := strconv.Atoi(healthStr)
+	if err != nil || healthSeconds <= 0 {
+		log.Warn("Invalid MQTT_HEALTH_INTERVAL_SECONDS value, using default of 60 seconds.")
+		healthSeconds = 60
+	}
+	config.MQTTHealthIntervalSecs = healthSeconds
+
+	if discovery := os.Getenv("MQTT_HA_DISCOVERY"); discovery != "" {
+		enabled, err := strconv.ParseBool(discovery)
+		if err != nil {
+			log.Warn("Invalid MQTT_HA_DISCOVERY value, Home Assistant discovery is disabled.")
+		}
+		config.HADiscovery = enabled
+	}
+	if grpcPortStr := os.Getenv("GRPC_PORT"); grpcPortStr != "" {
+		grpcPort, err := strconv.Atoi(grpcPortStr)
+		if err != nil || grpcPort <= 0 || grpcPort > 65535 {
+			log.Warn("Invalid GRPC_PORT value, the gRPC API is disabled.")
+		} else {
+			config.GRPCPort = grpcPort
+		}
+	}
+
+	config.APIKeys = loadAPIKeys(log)
+	maxAgeStr := os.Getenv("SIGNATURE_MAX_AGE_SECONDS")
+	if maxAgeStr == "" {
+		maxAgeStr = "300"
+	}
+	maxAge, err := strconv.Atoi(maxAgeStr)
+	if err != nil || maxAge <= 0 {
+		log.Warn("Invalid SIGNATURE_MAX_AGE_SECONDS value, using default of 300 seconds.")
+		maxAge = 300
+	}
+	config.SignatureMaxAgeSecs = maxAge
+	if pingAuth := os.Getenv("PING_REQUIRES_AUTH"); pingAuth != "" {
+		required, err := strconv.ParseBool(pingAuth)
+		if err != nil {
+			log.Warn("Invalid PING_REQUIRES_AUTH value, /ping does not require an API key.")
+		}
+		config.PingRequiresAuth = required
+	}
+
+	if rateStr := os.Getenv("RATE_LIMIT_PER_MINUTE"); rateStr != "" {
+		rate, err := strconv.Atoi(rateStr)
+		if err != nil || rate < 0 {
+			log.Warn("Invalid RATE_LIMIT_PER_MINUTE value, rate limiting is disabled.")
+			rate = 0
+		}
+		config.RateLimitPerMinute = rate
+	}
+	config.RateLimitBurst = config.RateLimitPerMinute
+	if burstStr := os.Getenv("RATE_LIMIT_BURST"); burstStr != "" {
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			log.Warnf("Invalid RATE_LIMIT_BURST value, using RATE_LIMIT_PER_MINUTE of %d.", config.RateLimitPerMinute)
+		} else {
+			config.RateLimitBurst = burst
+		}
+	}
+	if cooldownStr := os.Getenv("PAGE_COOLDOWN_SECONDS"); cooldownStr != "" {
+		cooldown, err := strconv.Atoi(cooldownStr)
+		if err != nil || cooldown < 0 {
+			log.Warn("Invalid PAGE_COOLDOWN_SECONDS value, page cooldowns are disabled.")
+			cooldown = 0
+		}
+		config.PageCooldownSecs = cooldown
+	}
+
+	// half configured TLS would otherwise fall back to plain HTTP without
+	// any client certificate checks
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if config.TLSClientCAFile != "" && config.TLSCertFile == "" {
+		return nil, errors.New("TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	switch config.TLSClientAuth {
+	case "":
+		config.TLSClientAuth = types.TLSClientAuthRequire
+	case types.TLSClientAuthRequire, types.TLSClientAuthOptional:
+	default:
+		log.Warn("Invalid TLS_CLIENT_AUTH value, client certificates are required.")
+		config.TLSClientAuth = types.TLSClientAuthRequire
+	}
+	config.TLSClients = loadTLSClients(log)
+
+	if config.ListenAddress == "" {
+		config.ListenAddress = ":9090"
+	}
+	if config.GRPCListenAddress == "" && !strings.HasPrefix(config.ListenAddress, "unix:") {
+		if host, _, err := net.SplitHostPort(config.ListenAddress); err == nil {
+			config.GRPCListenAddress = host
+		}
+	}
+	socketModeStr := os.Getenv("LISTEN_SOCKET_MODE")
+	if socketModeStr == "" {
+		socketModeStr = "0660"
+	}
+	socketMode, err := strconv.ParseUint(socketModeStr, 8, 32)
+	if err != nil || socketMode > 0o777 {
+		log.Warn("Invalid LISTEN_SOCKET_MODE value, using default of 0660.")
+		socketMode = 0o660
+	}
+	config.ListenSocketMode = uint32(socketMode)
+
+	shutdownStr := os.Getenv("SHUTDOWN_TIMEOUT_SECONDS")
+	if shutdownStr == "" {
+		shutdownStr = "30"
+	}
+	shutdownSeconds, err := strconv.Atoi(shutdownStr)
+	if err != nil || shutdownSeconds <= 0 {
+		log.Warn("Invalid SHUTDOWN_TIMEOUT_SECONDS value, using default of 30 seconds.")
+		shutdownSeconds = 30
+	}
+	config.ShutdownTimeoutSecs = shutdownSeconds
+	if cancelStr := os.Getenv("CANCEL_PAGES_ON_SHUTDOWN"); cancelStr != "" {
+		cancel, err := strconv.ParseBool(cancelStr)
+		if err != nil {
+			log.Warn("Invalid CANCEL_PAGES_ON_SHUTDOWN value, pages keep running on shutdown.")
+		}
+		config.CancelPagesOnShutdown = cancel
+	}
+
+	if compatStr := os.Getenv("HTTP_COMPAT_MODE"); compatStr != "" {
+		compat, err := strconv.ParseBool(compatStr)
+		if err != nil {
+			log.Warn("Invalid HTTP_COMPAT_MODE value, compatibility mode is disabled.")
+		}
+		config.HTTPCompatMode = compat
+	}
+
+	auditSizeStr := os.Getenv("AUDIT_LOG_MAX_SIZE_MB")
+	if auditSizeStr == "" {
+		auditSizeStr = "10"
+	}
+	auditSize, err := strconv.Atoi(auditSizeStr)
+	if err != nil || auditSize <= 0 {
+		log.Warn("Invalid AUDIT_LOG_MAX_SIZE_MB value, using default of 10 MB.")
+		auditSize = 10
+	}
+	config.AuditLogMaxSizeMB = auditSize
+	auditFilesStr := os.Getenv("AUDIT_LOG_MAX_FILES")
+	if auditFilesStr == "" {
+		auditFilesStr = "5"
+	}
+	auditFiles, err := strconv.Atoi(auditFilesStr)
+	if err != nil || auditFiles < 0 {
+		log.Warn("Invalid AUDIT_LOG_MAX_FILES value, using default of 5 files.")
+		auditFiles = 5
+	}
+	config.AuditLogMaxFiles = auditFiles
+
+	config.HADiscoveryPrefix = strings.Trim(os.Getenv("MQTT_HA_DISCOVERY_PREFIX"), "/")
+	if config.HADiscoveryPrefix == "" {
+		config.HADiscoveryPrefix = "homeassistant"
+	}
+
 	return config, nil
 }
+
+// splitList splits a comma separated value into its trimmed, non-empty parts.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// defaultSMTPPort returns the conventional port for the given SMTP TLS mode.
+func defaultSMTPPort(tlsMode string) string {
+	switch tlsMode {
+	case "tls":
+		return "465"
+	case "none":
+		return "25"
+	default:
+		return "587"
+	}
+}
@@ -283,3 +283,63 @@ func approxEqual(a, b float64) bool {
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestLoadConfig_IncompleteTLS(t *testing.T) {
+	tests := []struct {
+		description string
+		envVars     map[string]string
+		expected    string
+	}{
+		{"certificate without key", map[string]string{"TLS_CERT_FILE": "cert.pem"}, "TLS_CERT_FILE and TLS_KEY_FILE must be set together"},
+		{"key without certificate", map[string]string{"TLS_KEY_FILE": "key.pem"}, "TLS_CERT_FILE and TLS_KEY_FILE must be set together"},
+		{"client CA without certificate", map[string]string{"TLS_CLIENT_CA_FILE": "ca.pem"}, "TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			for key, value := range test.envVars {
+				t.Setenv(key, value)
+			}
+
+			log := logrus.New()
+			log.SetOutput(&logWriter{logs: &[]string{}})
+
+			_, err := LoadConfig(log)
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("Expected error '%s', got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_GRPCListenAddress(t *testing.T) {
+	tests := []struct {
+		description string
+		envVars     map[string]string
+		expected    string
+	}{
+		{"defaults to all interfaces", map[string]string{}, ""},
+		{"follows LISTEN_ADDRESS", map[string]string{"LISTEN_ADDRESS": "127.0.0.1:8080"}, "127.0.0.1"},
+		{"ignores a unix socket", map[string]string{"LISTEN_ADDRESS": "unix:/run/huproxy/huproxy.sock"}, ""},
+		{"set explicitly", map[string]string{"LISTEN_ADDRESS": "127.0.0.1:8080", "GRPC_LISTEN_ADDRESS": "10.0.0.5"}, "10.0.0.5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			for key, value := range test.envVars {
+				t.Setenv(key, value)
+			}
+
+			log := logrus.New()
+			log.SetOutput(&logWriter{logs: &[]string{}})
+
+			cfg, err := LoadConfig(log)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if cfg.GRPCListenAddress != test.expected {
+				t.Errorf("Expected GRPCListenAddress '%s', got '%s'", test.expected, cfg.GRPCListenAddress)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/YashdalfTheGray/huproxy/color"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loadProfiles builds the default profile from the top level config and
+// adds any profiles defined in spec. Profiles are separated by semicolons
+// and look like
+//
+//	name=groupedLightID,startColor,jumpColor,durationSeconds
+//
+// where any field left empty falls back to the default profile.
+func loadProfiles(config *types.Config, spec string, log *logrus.Logger) map[string]types.PageProfile {
+	defaultProfile := types.PageProfile{
+		Name:           types.DefaultProfile,
+		GroupedLightID: config.GroupedLightID,
+		StartColorHex:  config.StartColorHex,
+		JumpColorHex:   config.JumpColorHex,
+		StartColorXY:   config.StartColorXY,
+		JumpColorXY:    config.JumpColorXY,
+		DurationMS:     config.DurationMS,
+	}
+	profiles := map[string]types.PageProfile{types.DefaultProfile: defaultProfile}
+
+	for _, entry := range strings.Split(spec, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		name, fields, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			log.Warnf("Ignoring PAGE_PROFILES entry without a name: %s", entry)
+			continue
+		}
+		if name == types.NoProfile {
+			log.Warnf("Ignoring PAGE_PROFILES entry for %s, which is reserved", name)
+			continue
+		}
+
+		profile := defaultProfile
+		profile.Name = name
+
+		parts := strings.Split(fields, ",")
+		for len(parts) < 4 {
+			parts = append(parts, "")
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		if parts[0] != "" {
+			profile.GroupedLightID = parts[0]
+		}
+		if parts[1] != "" {
+			xy, err := color.HexToXY(parts[1])
+			if err != nil {
+				log.Warnf("Invalid start color for profile %s, using default.", name)
+			} else {
+				profile.StartColorHex, profile.StartColorXY = parts[1], xy
+			}
+		}
+		if parts[2] != "" {
+			xy, err := color.HexToXY(parts[2])
+			if err != nil {
+				log.Warnf("Invalid jump color for profile %s, using default.", name)
+			} else {
+				profile.JumpColorHex, profile.JumpColorXY = parts[2], xy
+			}
+		}
+		if parts[3] != "" {
+			seconds, err := strconv.Atoi(parts[3])
+			if err != nil || seconds <= 0 {
+				log.Warnf("Invalid duration for profile %s, using default.", name)
+			} else {
+				profile.DurationMS = seconds * 1000
+			}
+		}
+
+		profiles[name] = profile
+	}
+
+	return profiles
+}
+
+// loadRoutes parses the label routes in the named environment variable.
+// Routes are separated by semicolons and look like
+//
+//	label=value,otherLabel=otherValue:profile
+//
+// Routes that point at unknown profiles are dropped with a warning.
+func loadRoutes(envVar string, profiles map[string]types.PageProfile, log *logrus.Logger) []types.LabelRoute {
+	var routes []types.LabelRoute
+
+	for _, entry := range strings.Split(os.Getenv(envVar), ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(entry, ":")
+		if idx < 0 {
+			log.Warnf("Ignoring %s entry without a profile: %s", envVar, entry)
+			continue
+		}
+		profile := strings.TrimSpace(entry[idx+1:])
+		if _, ok := profiles[profile]; !ok {
+			log.Warnf("Ignoring %s entry for unknown profile %s", envVar, profile)
+			continue
+		}
+
+		route := types.LabelRoute{Matchers: map[string]string{}, Profile: profile}
+		for _, matcher := range strings.Split(entry[:idx], ",") {
+			name, value, ok := strings.Cut(matcher, "=")
+			if !ok {
+				continue
+			}
+			route.Matchers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// profileOrDefault returns name if it refers to a known profile, fallback
+// if it is empty, and fallback with a warning otherwise.
+func profileOrDefault(envVar, name, fallback string, profiles map[string]types.PageProfile, log *logrus.Logger) string {
+	if name == "" {
+		return fallback
+	}
+	if _, ok := profiles[name]; !ok {
+		log.Warnf("%s refers to unknown profile %s, using %s.", envVar, name, fallback)
+		return fallback
+	}
+	return name
+}
+
+// fallbackProfile is profileOrDefault for the default profile of routed
+// integrations, which can also be NoProfile.
+func fallbackProfile(envVar, name string, profiles map[string]types.PageProfile, log *logrus.Logger) string {
+	if name == types.NoProfile {
+		return name
+	}
+	return profileOrDefault(envVar, name, types.DefaultProfile, profiles, log)
+}
+
+// reservedIntegrations are the names of the built in integrations, which
+// generic webhooks can't reuse.
+var reservedIntegrations = []string{"alertmanager", "grafana", "pagerduty", "github", "opsgenie"}
+
+// loadGenericWebhooks reads the generic webhooks listed in GENERIC_WEBHOOKS,
+// each configured through GENERIC_<NAME>_* variables.
+func loadGenericWebhooks(profiles map[string]types.PageProfile, log *logrus.Logger) []types.GenericWebhook {
+	var webhooks []types.GenericWebhook
+
+	for _, name := range splitList(os.Getenv("GENERIC_WEBHOOKS")) {
+		name = strings.ToLower(name)
+		if slices.Contains(reservedIntegrations, name) {
+			log.Warnf("Ignoring generic webhook %s, the name is taken by a built in integration.", name)
+			continue
+		}
+
+		prefix := "GENERIC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		webhook := types.GenericWebhook{
+			Name:         name,
+			ActionPath:   os.Getenv(prefix + "ACTION_PATH"),
+			PageValues:   splitList(os.Getenv(prefix + "PAGE_VALUES")),
+			CancelValues: splitList(os.Getenv(prefix + "CANCEL_VALUES")),
+			ProfilePath:  os.Getenv(prefix + "PROFILE_PATH"),
+		}
+		if webhook.ActionPath == "" || len(webhook.PageValues) == 0 {
+			log.Warnf("Ignoring generic webhook %s, %sACTION_PATH and %sPAGE_VALUES are required.", name, prefix, prefix)
+			continue
+		}
+		webhook.Profile = profileOrDefault(prefix+"PROFILE", os.Getenv(prefix+"PROFILE"), types.DefaultProfile, profiles, log)
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks
+}
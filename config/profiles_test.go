@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	var logOutput []string
+	log := logrus.New()
+	log.SetOutput(&logWriter{logs: &logOutput})
+
+	cfg := &types.Config{
+		GroupedLightID: "group1",
+		StartColorHex:  "#ff5722",
+		JumpColorHex:   "#ff0000",
+		DurationMS:     15000,
+	}
+
+	profiles := loadProfiles(cfg, "critical=office,#ff0000,#0000ff,30; deploy=,#00ff00 ;broken=,nope;=nameless;none=office", log)
+
+	assert.Len(t, profiles, 4)
+	assert.Equal(t, "group1", profiles[types.DefaultProfile].GroupedLightID)
+
+	critical := profiles["critical"]
+	assert.Equal(t, "office", critical.GroupedLightID)
+	assert.Equal(t, "#0000ff", critical.JumpColorHex)
+	assert.Equal(t, 30000, critical.DurationMS)
+
+	deploy := profiles["deploy"]
+	assert.Equal(t, "group1", deploy.GroupedLightID)
+	assert.Equal(t, "#00ff00", deploy.StartColorHex)
+	assert.Equal(t, "#ff0000", deploy.JumpColorHex)
+	assert.Equal(t, 15000, deploy.DurationMS)
+
+	assert.Equal(t, "#ff5722", profiles["broken"].StartColorHex)
+	assert.Len(t, logOutput, 3)
+}
+
+func TestFallbackProfile(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(&logWriter{logs: &[]string{}})
+
+	profiles := map[string]types.PageProfile{types.DefaultProfile: {}, "critical": {}}
+
+	assert.Equal(t, types.DefaultProfile, fallbackProfile("TEST_PROFILE", "", profiles, log))
+	assert.Equal(t, "critical", fallbackProfile("TEST_PROFILE", "critical", profiles, log))
+	assert.Equal(t, types.DefaultProfile, fallbackProfile("TEST_PROFILE", "missing", profiles, log))
+	assert.Equal(t, types.NoProfile, fallbackProfile("TEST_PROFILE", "none", profiles, log))
+}
+
+func TestLoadRoutes(t *testing.T) {
+	var logOutput []string
+	log := logrus.New()
+	log.SetOutput(&logWriter{logs: &logOutput})
+
+	profiles := map[string]types.PageProfile{"critical": {}, "warning": {}}
+
+	os.Setenv("TEST_ROUTES", "severity=critical,team=infra:critical;severity=warning:warning;severity=info:missing")
+	defer os.Unsetenv("TEST_ROUTES")
+
+	routes := loadRoutes("TEST_ROUTES", profiles, log)
+
+	assert.Len(t, routes, 2)
+	assert.Equal(t, "critical", routes[0].Profile)
+	assert.True(t, routes[0].Matches(map[string]string{"severity": "critical", "team": "infra", "extra": "x"}))
+	assert.False(t, routes[0].Matches(map[string]string{"severity": "critical"}))
+	assert.Len(t, logOutput, 1)
+}
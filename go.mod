@@ -2,16 +2,28 @@ module github.com/YashdalfTheGray/huproxy
 
 go 1.23.2
 
-require github.com/sirupsen/logrus v1.9.3
+require (
+	github.com/eclipse/paho.mqtt.golang v1.5.0
+	github.com/mochi-mqtt/server/v2 v2.6.6
+	github.com/sirupsen/logrus v1.9.3
+	google.golang.org/grpc v1.67.1
+	google.golang.org/protobuf v1.34.2
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rs/xid v1.4.0 // indirect
+	golang.org/x/net v0.28.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/text v0.17.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
-	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/joho/godotenv v1.5.1
 	github.com/stretchr/testify v1.9.0
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/sys v0.24.0 // indirect
 )
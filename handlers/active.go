@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// PageEvent is sent to subscribers whenever a profile starts or stops
+// being paged.
+type PageEvent struct {
+	Profile string
+	Active  bool
+	Source  string
+	Time    time.Time
+}
+
+// ActivePage is a page that is currently running on the lights.
+type ActivePage struct {
+	Profile string
+	Source  string
+	Started time.Time
+	Until   time.Time
+}
+
+// pageTracker keeps track of running pages, expiring them once their
+// duration is up, and fans out PageEvents to subscribers.
+type pageTracker struct {
+	mu          sync.Mutex
+	active      map[string]*trackedPage
+	subscribers map[chan PageEvent]struct{}
+}
+
+type trackedPage struct {
+	ActivePage
+	timer *time.Timer
+}
+
+func newPageTracker() *pageTracker {
+	return &pageTracker{
+		active:      make(map[string]*trackedPage),
+		subscribers: make(map[chan PageEvent]struct{}),
+	}
+}
+
+func (t *pageTracker) started(profile, source string, duration time.Duration) {
+	now := time.Now()
+
+	t.mu.Lock()
+	if existing, ok := t.active[profile]; ok {
+		existing.timer.Stop()
+	}
+	page := &trackedPage{ActivePage: ActivePage{Profile: profile, Source: source, Started: now, Until: now.Add(duration)}}
+	page.timer = time.AfterFunc(duration, func() { t.expire(profile, page) })
+	t.active[profile] = page
+	t.publish(PageEvent{Profile: profile, Active: true, Source: source, Time: now})
+	t.mu.Unlock()
+}
+
+func (t *pageTracker) stopped(profile, source string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	page, ok := t.active[profile]
+	if !ok {
+		return
+	}
+	page.timer.Stop()
+	delete(t.active, profile)
+	t.publish(PageEvent{Profile: profile, Active: false, Source: source, Time: time.Now()})
+}
+
+// expire removes page once its duration is up, unless it has been
+// replaced by a newer page for the same profile in the meantime.
+func (t *pageTracker) expire(profile string, page *trackedPage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.active[profile] != page {
+		return
+	}
+	delete(t.active, profile)
+	t.publish(PageEvent{Profile: profile, Active: false, Source: page.Source, Time: time.Now()})
+}
+
+// publish hands the event to every subscriber without blocking, dropping
+// it for subscribers that have fallen behind. Callers must hold t.mu.
+func (t *pageTracker) publish(event PageEvent) {
+	for ch := range t.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (t *pageTracker) list() []ActivePage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pages := make([]ActivePage, 0, len(t.active))
+	for _, page := range t.active {
+		pages = append(pages, page.ActivePage)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Profile < pages[j].Profile })
+	return pages
+}
+
+// ActivePages returns the pages currently running, sorted by profile.
+func (h *Handler) ActivePages() []ActivePage {
+	return h.pages.list()
+}
+
+// SubscribePages returns a channel of PageEvents and a function that
+// unsubscribes and closes the channel.
+func (h *Handler) SubscribePages() (<-chan PageEvent, func()) {
+	ch := make(chan PageEvent, 16)
+
+	h.pages.mu.Lock()
+	h.pages.subscribers[ch] = struct{}{}
+	h.pages.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.pages.mu.Lock()
+			delete(h.pages.subscribers, ch)
+			h.pages.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// alertmanagerPayload is the subset of Alertmanager's webhook payload that
+// huproxy cares about.
+type alertmanagerPayload struct {
+	Version string              `json:"version"`
+	Status  string              `json:"status"`
+	Alerts  []alertmanagerAlert `json:"alerts"`
+}
+
+type alertmanagerAlert struct {
+	Status      string            `json:"status"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	Fingerprint string            `json:"fingerprint"`
+}
+
+// key identifies the alert across notifications by its fingerprint, or by
+// its labels if it has none.
+func (a alertmanagerAlert) key() string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+
+	names := make([]string, 0, len(a.Labels))
+	for name := range a.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=%q,", name, a.Labels[name])
+	}
+	return b.String()
+}
+
+// alertmanagerAdapter handles Alertmanager webhook notifications. Every
+// alert is mapped to a page profile through ALERTMANAGER_ROUTES, profiles
+// with a firing alert get paged and profiles whose alerts have all
+// resolved get cancelled.
+type alertmanagerAdapter struct {
+	config *types.Config
+	alerts alertTracker
+}
+
+func (a *alertmanagerAdapter) Name() string {
+	return "alertmanager"
+}
+
+func (a *alertmanagerAdapter) Parse(r *http.Request, body []byte) ([]Command, error) {
+	var payload alertmanagerPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidPayload, err)
+	}
+
+	return a.alerts.commands(a.config.AlertmanagerRoutes, payload.Alerts, a.config.AlertmanagerProfile), nil
+}
+
+// alertTracker remembers which alerts are firing for each profile across
+// notifications. Alertmanager and Grafana notify about every alert group
+// on its own, so one group resolving says nothing about the others.
+type alertTracker struct {
+	mu     sync.Mutex
+	firing map[string]map[string]bool
+}
+
+// commands routes every alert to a profile, dropping alerts routed to
+// NoProfile. Profiles with an alert firing in this notification get paged
+// and profiles whose alerts have now all resolved, in every group, get
+// cancelled.
+func (t *alertTracker) commands(routes []types.LabelRoute, alerts []alertmanagerAlert, fallback string) []Command {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.firing == nil {
+		t.firing = map[string]map[string]bool{}
+	}
+
+	paged := map[string]bool{}
+	for _, alert := range alerts {
+		profile := routeProfile(routes, alert.Labels, fallback)
+		if profile == types.NoProfile {
+			continue
+		}
+
+		if alert.Status == "firing" {
+			if t.firing[profile] == nil {
+				t.firing[profile] = map[string]bool{}
+			}
+			t.firing[profile][alert.key()] = true
+		} else {
+			delete(t.firing[profile], alert.key())
+		}
+		paged[profile] = paged[profile] || alert.Status == "firing"
+	}
+
+	profiles := make([]string, 0, len(paged))
+	for profile := range paged {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+
+	commands := make([]Command, 0, len(profiles))
+	for _, profile := range profiles {
+		switch {
+		case paged[profile]:
+			commands = append(commands, Command{Action: ActionPage, Profile: profile})
+		case len(t.firing[profile]) == 0:
+			delete(t.firing, profile)
+			commands = append(commands, Command{Action: ActionCancel, Profile: profile})
+		}
+	}
+	return commands
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertmanagerHandler(t *testing.T) {
+	tests := []struct {
+		description   string
+		payload       string
+		expectedCalls []bridgeCall
+	}{
+		{
+			description:   "firing critical alert pages the routed profile",
+			payload:       `{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"alertname":"Down","severity":"critical"}}]}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}},
+		},
+		{
+			description:   "resolved alert cancels the routed profile",
+			payload:       `{"version":"4","status":"resolved","alerts":[{"status":"resolved","labels":{"alertname":"Down","severity":"critical"}}]}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "no_signal"}},
+		},
+		{
+			description:   "unmatched alert falls back to the default profile",
+			payload:       `{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"severity":"info"}}]}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "group1", Signal: "alternating"}},
+		},
+		{
+			description: "one firing alert keeps the profile paged",
+			payload: `{"version":"4","status":"firing","alerts":[
+				{"status":"resolved","labels":{"severity":"critical"}},
+				{"status":"firing","labels":{"severity":"critical"}}]}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, _ := newTestHandler(bridge)
+			handler.Config.AlertmanagerRoutes = []types.LabelRoute{
+				{Matchers: map[string]string{"severity": "critical"}, Profile: "critical"},
+			}
+
+			rec := httptest.NewRecorder()
+			handler.IntegrationHandler(&alertmanagerAdapter{config: handler.Config})(rec, httptest.NewRequest("POST", "/integrations/alertmanager", strings.NewReader(test.payload)))
+
+			assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+			assert.Equal(t, test.expectedCalls, bridge.Calls())
+		})
+	}
+}
+
+func TestAlertmanagerHandler_NoDefaultProfile(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.AlertmanagerProfile = types.NoProfile
+	handler.Config.AlertmanagerRoutes = []types.LabelRoute{
+		{Matchers: map[string]string{"severity": "critical"}, Profile: "critical"},
+	}
+
+	rec := httptest.NewRecorder()
+	payload := `{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"severity":"info"}}]}`
+	handler.IntegrationHandler(&alertmanagerAdapter{config: handler.Config})(rec, httptest.NewRequest("POST", "/integrations/alertmanager", strings.NewReader(payload)))
+
+	assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+	assert.Empty(t, bridge.Calls())
+}
+
+func TestAlertmanagerHandler_Groups(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.AlertmanagerRoutes = []types.LabelRoute{
+		{Matchers: map[string]string{"severity": "critical"}, Profile: "critical"},
+	}
+	adapter := handler.IntegrationHandler(&alertmanagerAdapter{config: handler.Config})
+
+	notify := func(status, fingerprint string) {
+		payload := `{"version":"4","status":"` + status + `","alerts":[{"status":"` + status + `","fingerprint":"` + fingerprint + `","labels":{"severity":"critical"}}]}`
+		rec := httptest.NewRecorder()
+		adapter(rec, httptest.NewRequest("POST", "/integrations/alertmanager", strings.NewReader(payload)))
+		assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+	}
+
+	notify("firing", "db")
+	notify("firing", "api")
+	notify("resolved", "db")
+	assert.Equal(t, []bridgeCall{
+		{GroupedLightID: "office", Signal: "alternating"},
+		{GroupedLightID: "office", Signal: "alternating"},
+	}, bridge.Calls(), "the api group is still firing")
+
+	notify("resolved", "api")
+	assert.Equal(t, bridgeCall{GroupedLightID: "office", Signal: "no_signal"}, bridge.Calls()[2])
+}
+
+func TestAlertmanagerHandler_InvalidPayload(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+
+	rec := httptest.NewRecorder()
+	handler.IntegrationHandler(&alertmanagerAdapter{config: handler.Config})(rec, httptest.NewRequest("POST", "/integrations/alertmanager", strings.NewReader("nope")))
+
+	assert.Equal(t, "broke", decodeResponse(t, rec).Status)
+	assert.Empty(t, bridge.Calls())
+}
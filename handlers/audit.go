@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/audit"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultAuditLimit is how many entries /audit answers with when no
+	// limit is given.
+	defaultAuditLimit = 100
+	// maxAuditLimit is the most entries /audit answers with.
+	maxAuditLimit = 1000
+)
+
+// AuditResponse answers /audit with the matching audit log entries.
+type AuditResponse struct {
+	types.Response
+	Entries []audit.Entry `json:"entries"`
+}
+
+// AuditHandler answers with the audit log entries matching the since,
+// until, caller and limit query parameters. since and until are RFC 3339
+// times. limit defaults to defaultAuditLimit and is capped at
+// maxAuditLimit.
+func (h *Handler) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	log := h.requestLog(r)
+	log.Infof("Received /audit request from %s", r.RemoteAddr)
+
+	if h.Audit == nil {
+		h.writeResponse(w, types.Failure(types.ErrorConfigMissing, "AUDIT_LOG_FILE is not set"))
+		return
+	}
+
+	filter, err := auditFilter(r.URL.Query())
+	if err != nil {
+		log.Warnf("Invalid /audit query: %s", err)
+		h.writeResponse(w, types.Failure(types.ErrorInvalidQuery, err.Error()))
+		return
+	}
+
+	entries, err := h.Audit.Query(filter)
+	if err != nil {
+		log.Error("Failed to read audit log: ", err)
+		h.writeResponse(w, types.Failure(types.ErrorAuditFailed, "failed to read the audit log"))
+		return
+	}
+
+	response := AuditResponse{Response: types.Success(), Entries: entries}
+	response.RequestID = w.Header().Get(RequestIDHeader)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func auditFilter(query url.Values) (audit.Filter, error) {
+	filter := audit.Filter{Caller: query.Get("caller"), Limit: defaultAuditLimit}
+
+	var err error
+	if since := query.Get("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return filter, errors.New("since is not an RFC 3339 time")
+		}
+	}
+	if until := query.Get("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return filter, errors.New("until is not an RFC 3339 time")
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+			return filter, errors.New("limit is not a positive number")
+		}
+		filter.Limit = min(filter.Limit, maxAuditLimit)
+	}
+	return filter, nil
+}
+
+// auditEntry starts the audit log entry of a page or cancel of the
+// profile, timed from now.
+func auditEntry(log *logrus.Entry, source string, action Action, profile types.PageProfile) audit.Entry {
+	entry := audit.Entry{
+		Time:    time.Now(),
+		Source:  source,
+		Action:  action.String(),
+		Profile: profile.Name,
+		Target:  profile.GroupedLightID,
+	}
+	entry.RequestID, _ = entryRequestID(log)
+	entry.Caller, _ = entryCaller(log)
+	peer := entryPeer(log)
+	entry.RemoteAddr = peer.Addr
+	entry.Certificate = peer.Certificate
+	if action == ActionPage {
+		entry.Colors = []string{profile.StartColorHex, profile.JumpColorHex}
+		entry.DurationMS = profile.DurationMS
+	}
+	return entry
+}
+
+// record finishes the entry with the response and appends it to the audit
+// log, if there is one.
+func (h *Handler) record(log *logrus.Entry, entry audit.Entry, response types.Response) {
+	if h.Audit == nil {
+		return
+	}
+
+	entry.ElapsedMS = time.Since(entry.Time).Milliseconds()
+	entry.Outcome = response.Status
+	entry.Code = response.Code
+	entry.Message = response.Message
+	if response.Details != nil {
+		entry.BridgeStatus = response.Details.BridgeStatus
+		entry.BridgeErrors = response.Details.BridgeErrors
+	}
+
+	if err := h.Audit.Record(entry); err != nil {
+		log.Error("Failed to write audit log entry: ", err)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/audit"
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	assert.NoError(t, err)
+	defer auditLog.Close()
+	handler.Audit = auditLog
+
+	page := func(caller string, profile string) {
+		req := httptest.NewRequest("POST", "/page?profile="+profile, nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: caller}}}}}
+		req.Header.Set(RequestIDHeader, "req-"+caller)
+		req = req.WithContext(WithCaller(req.Context(), types.Caller{Name: caller}))
+		handler.RequestID(handler.PageHandler)(httptest.NewRecorder(), req)
+	}
+
+	page("ci", "critical")
+	bridge.StatusCode = http.StatusForbidden
+	page("ops", "")
+	handler.RunCommands("MQTT", []Command{{Action: ActionCancel, Profile: "critical"}})
+
+	query := func(target string) (int, AuditResponse) {
+		rec := httptest.NewRecorder()
+		handler.AuditHandler(rec, httptest.NewRequest("GET", target, nil))
+
+		var response AuditResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+		return rec.Code, response
+	}
+
+	code, response := query("/audit")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, response.Entries, 3)
+
+	first := response.Entries[0]
+	assert.Equal(t, "req-ci", first.RequestID)
+	assert.Equal(t, "ci", first.Caller)
+	assert.Equal(t, "192.0.2.1:1234", first.RemoteAddr)
+	assert.Equal(t, "CN=ci", first.Certificate)
+	assert.Equal(t, "PageHandler", first.Source)
+	assert.Equal(t, "page", first.Action)
+	assert.Equal(t, "critical", first.Profile)
+	assert.Equal(t, "office", first.Target)
+	assert.Equal(t, 30000, first.DurationMS)
+	assert.Equal(t, http.StatusOK, first.BridgeStatus)
+	assert.Equal(t, "okay", first.Outcome)
+
+	second := response.Entries[1]
+	assert.Equal(t, "ops", second.Caller)
+	assert.Equal(t, "broke", second.Outcome)
+	assert.Equal(t, types.ErrorBridgeRejected, second.Code)
+	assert.Equal(t, http.StatusForbidden, second.BridgeStatus)
+
+	third := response.Entries[2]
+	assert.Equal(t, "MQTT", third.Source)
+	assert.Equal(t, "cancel", third.Action)
+	assert.Empty(t, third.Caller)
+
+	_, response = query("/audit?caller=ops")
+	assert.Len(t, response.Entries, 1)
+	assert.Equal(t, "ops", response.Entries[0].Caller)
+
+	_, response = query("/audit?until=" + first.Time.Format(time.RFC3339))
+	assert.Empty(t, response.Entries)
+
+	_, response = query("/audit?limit=1")
+	assert.Equal(t, []audit.Entry{third}, response.Entries)
+
+	code, response = query("/audit?since=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, types.ErrorInvalidQuery, response.Code)
+
+	code, response = query("/audit?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, types.ErrorInvalidQuery, response.Code)
+}
+
+func TestAuditFilter_Limit(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"", defaultAuditLimit},
+		{"limit=5", 5},
+		{"limit=5000", maxAuditLimit},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		filter, err := auditFilter(query)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, filter.Limit, test.query)
+	}
+}
+
+func TestAudit_NotConfigured(t *testing.T) {
+	handler, _ := newTestHandler(newFakeBridge(t))
+
+	rec := httptest.NewRecorder()
+	handler.AuditHandler(rec, httptest.NewRequest("GET", "/audit", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, types.ErrorConfigMissing, decodeResponse(t, rec).Code)
+}
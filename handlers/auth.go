@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+type callerKey struct{}
+
+// WithCaller returns a copy of ctx carrying the authenticated caller.
+func WithCaller(ctx context.Context, caller types.Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller authenticated for the request, if
+// any.
+func CallerFromContext(ctx context.Context) (types.Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(types.Caller)
+	return caller, ok
+}
+
+type peerKey struct{}
+
+// Peer is where a request came from: its remote address and the subject of
+// its verified client certificate, if any.
+type Peer struct {
+	Addr        string
+	Certificate string
+}
+
+// WithPeer returns a copy of ctx carrying where the request came from, for
+// the audit log of commands run through RunCommandsContext.
+func WithPeer(ctx context.Context, peer Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, peer)
+}
+
+// Authenticated runs the auth middlewares, then rate limiting, in front
+// of a control endpoint.
+func (h *Handler) Authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return h.VerifyClientCert(h.VerifySignature(h.RequireAPIKey(h.RateLimit(next))))
+}
+
+// VerifyClientCert identifies callers that presented a verified client
+// certificate as the TLS client whose names include the certificate's
+// common name or one of its subject alternative names, rejecting
+// certificates that match none. Without any TLS clients configured, every
+// verified certificate is let through, named after its common name.
+// Requests without a verified certificate are passed on untouched.
+func (h *Handler) VerifyClientCert(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next(w, r)
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		caller, ok := h.CertCaller(cert)
+		if !ok {
+			h.requestLog(r).Warnf("Rejected client certificate %s from %s", cert.Subject.CommonName, r.RemoteAddr)
+			writeJSON(w, types.Failure(types.ErrorForbidden, "certificate not allowed"))
+			return
+		}
+
+		next(w, r.WithContext(WithCaller(r.Context(), caller)))
+	}
+}
+
+// CertCaller finds the TLS client a verified client certificate belongs
+// to, see VerifyClientCert.
+func (h *Handler) CertCaller(cert *x509.Certificate) (types.Caller, bool) {
+	if len(h.Config.TLSClients) == 0 {
+		return types.Caller{Name: cert.Subject.CommonName}, true
+	}
+
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+
+	for _, client := range h.Config.TLSClients {
+		for _, name := range names {
+			if name != "" && slices.Contains(client.Names, name) {
+				return client.Caller, true
+			}
+		}
+	}
+	return types.Caller{}, false
+}
+
+// RequireAPIKey only lets requests carrying one of the configured API keys,
+// as a bearer token or in X-API-Key, through to next. Requests pass
+// through untouched when no keys are configured or an earlier middleware,
+// like VerifySignature or VerifyClientCert, already authenticated the
+// caller.
+func (h *Handler) RequireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := CallerFromContext(r.Context()); ok || len(h.Config.APIKeys) == 0 {
+			next(w, r)
+			return
+		}
+
+		key, ok := h.APIKey(requestAPIKey(r))
+		if !ok {
+			h.requestLog(r).Warnf("Rejected unauthenticated %s request from %s", r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="huproxy"`)
+			writeJSON(w, types.Failure(types.ErrorUnauthorized, "unauthorized"))
+			return
+		}
+
+		next(w, r.WithContext(WithCaller(r.Context(), key.Caller)))
+	}
+}
+
+// APIKey finds the configured bearer key matching presented. Every key is
+// compared in constant time so the timing doesn't give away which one
+// was close.
+func (h *Handler) APIKey(presented string) (types.APIKey, bool) {
+	var match types.APIKey
+	found := false
+	for _, key := range h.Config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(key.Key)) == 1 && key.Accepts(types.AuthBearer) {
+			match = key
+			found = true
+		}
+	}
+	return match, found
+}
+
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// authorize checks that the request's caller, if any, may use the
+// profile, writing a 403 if not.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, profile types.PageProfile) bool {
+	caller, ok := CallerFromContext(r.Context())
+	if !ok || caller.Allows(profile) {
+		return true
+	}
+
+	h.requestLog(r).Warnf("Caller %s is not allowed to use profile %s", caller.Name, profile.Name)
+	writeJSON(w, types.Failure(types.ErrorForbidden, "profile not allowed"))
+	return false
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAPIKey(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, notifier := newTestHandler(bridge)
+	handler.Config.APIKeys = []types.APIKey{
+		{Caller: types.Caller{Name: "ci", Profiles: []string{types.DefaultProfile}}, Key: "secret1", Auth: types.AuthAny},
+		{Caller: types.Caller{Name: "ops"}, Key: "secret2", Auth: types.AuthBearer},
+	}
+	bridge.StatusCode = http.StatusForbidden
+	page := handler.RequireAPIKey(handler.PageHandler)
+
+	tests := []struct {
+		description string
+		url         string
+		header      string
+		value       string
+		statusCode  int
+	}{
+		{"no key", "/page", "", "", http.StatusUnauthorized},
+		{"wrong key", "/page", "X-API-Key", "nope", http.StatusUnauthorized},
+		{"profile not allowed", "/page?profile=critical", "Authorization", "Bearer secret1", http.StatusForbidden},
+		{"bearer token", "/page", "Authorization", "Bearer secret1", http.StatusBadGateway},
+		{"header", "/page?profile=critical", "X-API-Key", "secret2", http.StatusBadGateway},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.url, nil)
+			if test.header != "" {
+				req.Header.Set(test.header, test.value)
+			}
+			rec := httptest.NewRecorder()
+			page(rec, req)
+			assert.Equal(t, test.statusCode, rec.Code)
+		})
+	}
+
+	assert.Len(t, bridge.Calls(), 2)
+	assert.Contains(t, notifier.messages[0], "[PageHandler (ci)]")
+	assert.Contains(t, notifier.messages[1], "[PageHandler (ops)]")
+}
+
+func TestRequireAPIKey_NoKeysConfigured(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+
+	rec := httptest.NewRecorder()
+	handler.RequireAPIKey(handler.PingHandler)(rec, httptest.NewRequest("GET", "/ping", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+}
+
+func TestVerifyClientCert(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, notifier := newTestHandler(bridge)
+	handler.Config.TLSClients = []types.TLSClient{
+		{Caller: types.Caller{Name: "ci", Profiles: []string{types.DefaultProfile}}, Names: []string{"ci.example.com"}},
+	}
+	bridge.StatusCode = http.StatusForbidden
+	page := handler.VerifyClientCert(handler.PageHandler)
+
+	withCert := func(req *http.Request, cert *x509.Certificate) *http.Request {
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return req
+	}
+
+	tests := []struct {
+		description string
+		req         *http.Request
+		statusCode  int
+	}{
+		{"no certificate", httptest.NewRequest("GET", "/page", nil), http.StatusBadGateway},
+		{"SAN match", withCert(httptest.NewRequest("GET", "/page", nil), &x509.Certificate{DNSNames: []string{"ci.example.com"}}), http.StatusBadGateway},
+		{"profile not allowed", withCert(httptest.NewRequest("GET", "/page?profile=critical", nil), &x509.Certificate{Subject: pkix.Name{CommonName: "ci.example.com"}}), http.StatusForbidden},
+		{"unknown certificate", withCert(httptest.NewRequest("GET", "/page", nil), &x509.Certificate{Subject: pkix.Name{CommonName: "laptop"}}), http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			page(rec, test.req)
+			assert.Equal(t, test.statusCode, rec.Code)
+		})
+	}
+
+	assert.Len(t, bridge.Calls(), 2)
+	assert.Contains(t, notifier.messages[1], "[PageHandler (ci)]")
+}
+
+func TestIntegrationRoute(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.APIKeys = []types.APIKey{
+		{Caller: types.Caller{Name: "alertmanager"}, Key: "secret1", Auth: types.AuthAny},
+		{Caller: types.Caller{Name: "ci", Profiles: []string{"critical"}}, Key: "secret2", Auth: types.AuthAny},
+	}
+	handler.Config.PagerDutySecret = "pdsecret"
+
+	routes := map[string]http.HandlerFunc{}
+	for _, adapter := range handler.Integrations() {
+		routes[adapter.Name()] = handler.IntegrationRoute(adapter)
+	}
+	payload := `{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"severity":"info"}}]}`
+
+	tests := []struct {
+		description string
+		integration string
+		key         string
+		statusCode  int
+		code        types.ErrorCode
+	}{
+		{"alertmanager without a key", "alertmanager", "", http.StatusUnauthorized, types.ErrorUnauthorized},
+		{"alertmanager with a key", "alertmanager", "secret1", http.StatusOK, ""},
+		{"alertmanager routing to a profile the key may not use", "alertmanager", "secret2", http.StatusForbidden, types.ErrorForbidden},
+		{"opsgenie without a key", "opsgenie", "", http.StatusUnauthorized, types.ErrorUnauthorized},
+		{"pagerduty checks its own signature instead", "pagerduty", "", http.StatusUnauthorized, types.ErrorInvalidSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/integrations/"+test.integration, strings.NewReader(payload))
+			if test.key != "" {
+				req.Header.Set("X-API-Key", test.key)
+			}
+			rec := httptest.NewRecorder()
+			routes[test.integration](rec, req)
+
+			assert.Equal(t, test.statusCode, rec.Code)
+			assert.Equal(t, test.code, decodeResponse(t, rec).Code)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// genericAdapter handles a GenericWebhook. The value at ActionPath decides
+// between paging and cancelling, and the value at ProfilePath, if set and a
+// known profile, picks the profile.
+type genericAdapter struct {
+	webhook types.GenericWebhook
+}
+
+func (a *genericAdapter) Name() string {
+	return a.webhook.Name
+}
+
+func (a *genericAdapter) Parse(r *http.Request, body []byte) ([]Command, error) {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidPayload, err)
+	}
+
+	value, ok := lookupJSONPath(document, a.webhook.ActionPath)
+	if !ok {
+		return nil, fmt.Errorf("%w: nothing found at %s", errInvalidPayload, a.webhook.ActionPath)
+	}
+
+	profile := a.webhook.Profile
+	if a.webhook.ProfilePath != "" {
+		if name, ok := lookupJSONPath(document, a.webhook.ProfilePath); ok && name != "" {
+			profile = name
+		}
+	}
+
+	switch {
+	case slices.Contains(a.webhook.PageValues, value):
+		return []Command{{Action: ActionPage, Profile: profile}}, nil
+	case slices.Contains(a.webhook.CancelValues, value):
+		return []Command{{Action: ActionCancel, Profile: profile}}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	var document interface{}
+	err := json.Unmarshal([]byte(`{"status":"down","check":{"tags":["db","prod"],"attempts":3,"muted":false}}`), &document)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		expected string
+		found    bool
+	}{
+		{"$.status", "down", true},
+		{"$.check.tags[1]", "prod", true},
+		{"$.check.attempts", "3", true},
+		{"$.check.muted", "false", true},
+		{"$.check", "", false},
+		{"$.check.tags[2]", "", false},
+		{"$.missing.field", "", false},
+		{"status", "", false},
+	}
+
+	for _, test := range tests {
+		value, found := lookupJSONPath(document, test.path)
+		assert.Equal(t, test.found, found, test.path)
+		assert.Equal(t, test.expected, value, test.path)
+	}
+}
+
+func TestGenericAdapter(t *testing.T) {
+	tests := []struct {
+		description    string
+		payload        string
+		expectedStatus string
+		expectedCalls  []bridgeCall
+	}{
+		{
+			description:    "page value pages the profile from the payload",
+			payload:        `{"status":"down","monitor":{"profile":"critical"}}`,
+			expectedStatus: "okay",
+			expectedCalls:  []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}},
+		},
+		{
+			description:    "cancel value without a profile cancels the default profile",
+			payload:        `{"status":"up"}`,
+			expectedStatus: "okay",
+			expectedCalls:  []bridgeCall{{GroupedLightID: "group1", Signal: "no_signal"}},
+		},
+		{
+			description:    "other values are ignored",
+			payload:        `{"status":"paused"}`,
+			expectedStatus: "okay",
+		},
+		{
+			description:    "missing action is rejected",
+			payload:        `{"state":"down"}`,
+			expectedStatus: "broke",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, _ := newTestHandler(bridge)
+			adapter := &genericAdapter{webhook: types.GenericWebhook{
+				Name:         "uptime",
+				ActionPath:   "$.status",
+				PageValues:   []string{"down"},
+				CancelValues: []string{"up"},
+				ProfilePath:  "$.monitor.profile",
+				Profile:      types.DefaultProfile,
+			}}
+
+			rec := httptest.NewRecorder()
+			handler.IntegrationHandler(adapter)(rec, httptest.NewRequest("POST", "/integrations/uptime", strings.NewReader(test.payload)))
+
+			assert.Equal(t, test.expectedStatus, decodeResponse(t, rec).Status)
+			assert.Equal(t, test.expectedCalls, bridge.Calls())
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// gitHubPayload is the union of the fields huproxy reads from the
+// workflow_run, check_suite and deployment_status webhook payloads.
+type gitHubPayload struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	WorkflowRun struct {
+		Name       string `json:"name"`
+		HeadBranch string `json:"head_branch"`
+		Conclusion string `json:"conclusion"`
+	} `json:"workflow_run"`
+	CheckSuite struct {
+		HeadBranch string `json:"head_branch"`
+		Conclusion string `json:"conclusion"`
+	} `json:"check_suite"`
+	DeploymentStatus struct {
+		State string `json:"state"`
+	} `json:"deployment_status"`
+	Deployment struct {
+		Environment string `json:"environment"`
+	} `json:"deployment"`
+}
+
+// gitHubAdapter handles GitHub webhooks. Failed workflow runs and check
+// suites on the watched branches page GITHUB_FAILURE_PROFILE and successful
+// ones cancel it. Deployments to the watched environments page
+// GITHUB_SUCCESS_PROFILE when they succeed and GITHUB_FAILURE_PROFILE when
+// they fail.
+type gitHubAdapter struct {
+	config *types.Config
+}
+
+func (a *gitHubAdapter) Name() string {
+	return "github"
+}
+
+// VerifiesSignature is true, every webhook has to carry a valid
+// X-Hub-Signature-256.
+func (a *gitHubAdapter) VerifiesSignature() bool {
+	return true
+}
+
+func (a *gitHubAdapter) Parse(r *http.Request, body []byte) ([]Command, error) {
+	if a.config.GitHubSecret == "" {
+		return nil, fmt.Errorf("%w: GITHUB_WEBHOOK_SECRET is not set", errNotConfigured)
+	}
+
+	signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+	if !ok || !validHMACSHA256(a.config.GitHubSecret, body, signature) {
+		return nil, errInvalidSignature
+	}
+
+	var payload gitHubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidPayload, err)
+	}
+
+	if len(a.config.GitHubRepos) > 0 && !slices.Contains(a.config.GitHubRepos, payload.Repository.FullName) {
+		return nil, nil
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "workflow_run":
+		run := payload.WorkflowRun
+		if payload.Action == "completed" && slices.Contains(a.config.GitHubBranches, run.HeadBranch) &&
+			(len(a.config.GitHubWorkflows) == 0 || slices.Contains(a.config.GitHubWorkflows, run.Name)) {
+			return a.buildOutcome(run.Conclusion), nil
+		}
+	case "check_suite":
+		suite := payload.CheckSuite
+		if payload.Action == "completed" && slices.Contains(a.config.GitHubBranches, suite.HeadBranch) {
+			return a.buildOutcome(suite.Conclusion), nil
+		}
+	case "deployment_status":
+		if slices.Contains(a.config.GitHubEnvironments, payload.Deployment.Environment) {
+			return a.deployOutcome(payload.DeploymentStatus.State), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// buildOutcome pages the failure profile for failed builds and cancels it
+// once a build succeeds again.
+func (a *gitHubAdapter) buildOutcome(conclusion string) []Command {
+	switch conclusion {
+	case "failure", "timed_out", "startup_failure":
+		return []Command{{Action: ActionPage, Profile: a.config.GitHubFailureProfile}}
+	case "success":
+		return []Command{{Action: ActionCancel, Profile: a.config.GitHubFailureProfile}}
+	default:
+		return nil
+	}
+}
+
+// deployOutcome pages the success or failure profile for finished
+// deployments.
+func (a *gitHubAdapter) deployOutcome(state string) []Command {
+	switch state {
+	case "failure", "error":
+		return []Command{{Action: ActionPage, Profile: a.config.GitHubFailureProfile}}
+	case "success":
+		if a.config.GitHubSuccessProfile == "" {
+			return nil
+		}
+		return []Command{{Action: ActionPage, Profile: a.config.GitHubSuccessProfile}}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signGitHub(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGitHubHandler(t *testing.T) {
+	tests := []struct {
+		description   string
+		event         string
+		payload       string
+		expectedCalls []bridgeCall
+	}{
+		{
+			description:   "failed build on main pages the failure profile",
+			event:         "workflow_run",
+			payload:       `{"action":"completed","repository":{"full_name":"acme/app"},"workflow_run":{"name":"CI","head_branch":"main","conclusion":"failure"}}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}},
+		},
+		{
+			description:   "successful build on main cancels the failure profile",
+			event:         "check_suite",
+			payload:       `{"action":"completed","repository":{"full_name":"acme/app"},"check_suite":{"head_branch":"main","conclusion":"success"}}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "no_signal"}},
+		},
+		{
+			description: "failed build on another branch is ignored",
+			event:       "workflow_run",
+			payload:     `{"action":"completed","repository":{"full_name":"acme/app"},"workflow_run":{"name":"CI","head_branch":"feature","conclusion":"failure"}}`,
+		},
+		{
+			description: "failed build of an unwatched workflow is ignored",
+			event:       "workflow_run",
+			payload:     `{"action":"completed","repository":{"full_name":"acme/app"},"workflow_run":{"name":"Lint","head_branch":"main","conclusion":"failure"}}`,
+		},
+		{
+			description: "unwatched repository is ignored",
+			event:       "workflow_run",
+			payload:     `{"action":"completed","repository":{"full_name":"acme/other"},"workflow_run":{"name":"CI","head_branch":"main","conclusion":"failure"}}`,
+		},
+		{
+			description:   "successful production deploy pages the success profile",
+			event:         "deployment_status",
+			payload:       `{"action":"created","repository":{"full_name":"acme/app"},"deployment_status":{"state":"success"},"deployment":{"environment":"production"}}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "group1", Signal: "alternating"}},
+		},
+		{
+			description: "staging deploy is ignored",
+			event:       "deployment_status",
+			payload:     `{"action":"created","repository":{"full_name":"acme/app"},"deployment_status":{"state":"success"},"deployment":{"environment":"staging"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, _ := newTestHandler(bridge)
+			handler.Config.GitHubSecret = "secret"
+			handler.Config.GitHubRepos = []string{"acme/app"}
+			handler.Config.GitHubBranches = []string{"main"}
+			handler.Config.GitHubWorkflows = []string{"CI"}
+			handler.Config.GitHubEnvironments = []string{"production"}
+			handler.Config.GitHubFailureProfile = "critical"
+			handler.Config.GitHubSuccessProfile = "default"
+
+			req := httptest.NewRequest("POST", "/integrations/github", strings.NewReader(test.payload))
+			req.Header.Set("X-GitHub-Event", test.event)
+			req.Header.Set("X-Hub-Signature-256", signGitHub("secret", test.payload))
+
+			rec := httptest.NewRecorder()
+			handler.IntegrationHandler(&gitHubAdapter{config: handler.Config})(rec, req)
+
+			assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+			assert.Equal(t, test.expectedCalls, bridge.Calls())
+		})
+	}
+}
+
+func TestGitHubHandler_InvalidSignature(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.GitHubSecret = "secret"
+
+	payload := `{"action":"completed"}`
+	req := httptest.NewRequest("POST", "/integrations/github", strings.NewReader(payload))
+	req.Header.Set("X-GitHub-Event", "workflow_run")
+	req.Header.Set("X-Hub-Signature-256", signGitHub("wrong", payload))
+
+	rec := httptest.NewRecorder()
+	handler.IntegrationHandler(&gitHubAdapter{config: handler.Config})(rec, req)
+
+	assert.Equal(t, "broke", decodeResponse(t, rec).Status)
+	assert.Empty(t, bridge.Calls())
+}
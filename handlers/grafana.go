@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// grafanaPayload is the subset of a Grafana webhook contact point payload
+// that huproxy cares about. The alerts follow Alertmanager's format, with
+// the alert rule's folder in the grafana_folder label.
+type grafanaPayload struct {
+	Status string              `json:"status"`
+	Title  string              `json:"title"`
+	Alerts []alertmanagerAlert `json:"alerts"`
+}
+
+// grafanaAdapter handles notifications from a Grafana webhook contact
+// point. Alerts are routed to profiles through GRAFANA_ROUTES, where
+// folder=<name> can be used as a shorthand for the grafana_folder label.
+type grafanaAdapter struct {
+	config *types.Config
+	alerts alertTracker
+}
+
+func (a *grafanaAdapter) Name() string {
+	return "grafana"
+}
+
+func (a *grafanaAdapter) Parse(r *http.Request, body []byte) ([]Command, error) {
+	var payload grafanaPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidPayload, err)
+	}
+
+	for _, alert := range payload.Alerts {
+		if alert.Labels == nil {
+			continue
+		}
+		if folder, ok := alert.Labels["grafana_folder"]; ok {
+			if _, taken := alert.Labels["folder"]; !taken {
+				alert.Labels["folder"] = folder
+			}
+		}
+	}
+
+	return a.alerts.commands(a.config.GrafanaRoutes, payload.Alerts, a.config.GrafanaProfile), nil
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrafanaHandler(t *testing.T) {
+	tests := []struct {
+		description    string
+		defaultProfile string
+		payload        string
+		expectedCalls  []bridgeCall
+	}{
+		{
+			description:   "firing alert in a routed folder pages the profile",
+			payload:       `{"status":"firing","title":"[FIRING:1] High latency","alerts":[{"status":"firing","labels":{"alertname":"High latency","grafana_folder":"Production"}}]}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}},
+		},
+		{
+			description:   "resolved alert in a routed folder cancels the profile",
+			payload:       `{"status":"resolved","alerts":[{"status":"resolved","labels":{"alertname":"High latency","grafana_folder":"Production"}}]}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "no_signal"}},
+		},
+		{
+			description:   "alert in another folder uses the default profile",
+			payload:       `{"status":"firing","alerts":[{"status":"firing","labels":{"grafana_folder":"Staging"}}]}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "group1", Signal: "alternating"}},
+		},
+		{
+			description:    "alert in another folder is dropped without a default profile",
+			defaultProfile: types.NoProfile,
+			payload:        `{"status":"firing","alerts":[{"status":"firing","labels":{"grafana_folder":"Staging"}}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, _ := newTestHandler(bridge)
+			handler.Config.GrafanaProfile = types.DefaultProfile
+			if test.defaultProfile != "" {
+				handler.Config.GrafanaProfile = test.defaultProfile
+			}
+			handler.Config.GrafanaRoutes = []types.LabelRoute{
+				{Matchers: map[string]string{"folder": "Production"}, Profile: "critical"},
+			}
+
+			rec := httptest.NewRecorder()
+			handler.IntegrationHandler(&grafanaAdapter{config: handler.Config})(rec, httptest.NewRequest("POST", "/integrations/grafana", strings.NewReader(test.payload)))
+
+			assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+			assert.Equal(t, test.expectedCalls, bridge.Calls())
+		})
+	}
+}
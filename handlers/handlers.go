@@ -1,13 +1,15 @@
 package handlers
 
 import (
-	"bytes"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/YashdalfTheGray/huproxy/audit"
 	"github.com/YashdalfTheGray/huproxy/types"
 
 	"github.com/sirupsen/logrus"
@@ -17,121 +19,223 @@ type Handler struct {
 	Config   *types.Config
 	Log      *logrus.Logger
 	Notifier types.Notifier
+	// Audit records every page and cancel, if set.
+	Audit *audit.Log
+
+	pages     *pageTracker
+	nonces    *nonceCache
+	limiter   *rateLimiter
+	cooldowns *cooldownTracker
 }
 
 // NewHandler creates a new Handler with the given Config and Logger.
 func NewHandler(config *types.Config, log *logrus.Logger, notifier types.Notifier) *Handler {
-	return &Handler{
-		Config:   config,
-		Log:      log,
-		Notifier: notifier,
+	h := &Handler{
+		Config:    config,
+		Log:       log,
+		Notifier:  notifier,
+		pages:     newPageTracker(),
+		nonces:    newNonceCache(),
+		cooldowns: newCooldownTracker(),
+	}
+	if config.RateLimitPerMinute > 0 {
+		h.limiter = newRateLimiter(config.RateLimitPerMinute, config.RateLimitBurst)
 	}
+	return h
 }
 
 func (h *Handler) PingHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Infof("Received /ping request from %s", r.RemoteAddr)
-	var response types.Response
+	log := h.requestLog(r)
+	log.Infof("Received /ping request from %s", r.RemoteAddr)
+	h.writeResponse(w, h.ping(log, "PingHandler"))
+}
 
+// Ping reports whether the bridge settings are configured, naming source
+// as the origin of any notification.
+func (h *Handler) Ping(source string) types.Response {
+	return h.ping(logrus.NewEntry(h.Log), source)
+}
+
+func (h *Handler) ping(log *logrus.Entry, source string) types.Response {
 	if h.Config.BridgeAddress == "" || h.Config.GroupedLightID == "" || h.Config.HueUsername == "" {
-		response = types.Error("")
-		h.Log.Warn("Missing one or more environment variables.")
-		h.Notifier.SendErrorNotification("[PingHandler] Missing one or more environment variables.")
-	} else {
-		response = types.Success()
+		log.Warn("Missing one or more environment variables.")
+		h.notify(log, source, types.NotificationEvent{Level: types.LogLevelError, Status: types.NotificationFiring, Message: "Missing one or more environment variables."})
+		return types.Failure(types.ErrorConfigMissing, "one or more of HUE_BRIDGE_ADDRESS, GROUPED_LIGHT_ID and HUE_USERNAME is not set")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return types.Success()
 }
 
 func (h *Handler) PageHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Infof("Received /page request from %s", r.RemoteAddr)
-
-	if h.Config.BridgeAddress == "" || h.Config.GroupedLightID == "" || h.Config.HueUsername == "" {
-		response := types.Error("")
-		h.Log.Warn("Environment variables are not properly set.")
-		h.Notifier.SendErrorNotification("[PageHandler] Environment variables are not properly set.")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+	source := "PageHandler"
+	log := h.requestLog(r)
+	log.Infof("Received /page request from %s", r.RemoteAddr)
+
+	profile, ok := h.Profile(r.URL.Query().Get("profile"))
+	if !ok {
+		log.Warnf("Unknown page profile requested: %s", r.URL.Query().Get("profile"))
+		h.writeResponse(w, types.Failure(types.ErrorUnknownProfile, "unknown profile"))
 		return
 	}
+	if !h.authorize(w, r, profile) {
+		return
+	}
+	h.writeResponse(w, h.runPage(log, source, profile))
+}
 
-	url := "https://" + h.Config.BridgeAddress + "/clip/v2/resource/grouped_light/" + h.Config.GroupedLightID
+func (h *Handler) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	source := "CancelHandler"
+	log := h.requestLog(r)
+	log.Infof("Received /cancel request from %s", r.RemoteAddr)
 
-	body := map[string]interface{}{
-		"signaling": map[string]interface{}{
-			"signal":   "alternating",
-			"duration": h.Config.DurationMS,
-			"colors": []map[string]interface{}{
-				{"xy": h.Config.StartColorXY},
-				{"xy": h.Config.JumpColorXY},
-			},
-		},
+	profile, ok := h.Profile(r.URL.Query().Get("profile"))
+	if !ok {
+		log.Warnf("Unknown page profile requested: %s", r.URL.Query().Get("profile"))
+		h.writeResponse(w, types.Failure(types.ErrorUnknownProfile, "unknown profile"))
+		return
 	}
-
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		response := types.Error("")
-		h.Log.Error("Failed to stringify Hue API JSON request body.")
-		h.Notifier.SendErrorNotification("[PageHandler] Failed to stringify Hue API JSON request body.")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+	if !h.authorize(w, r, profile) {
 		return
 	}
 
-	// custom http client that ignores certificate verification
-	// this is some shit that the hue api imposes on us
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+	h.writeResponse(w, h.runCancel(log, source, profile))
+}
+
+// Profile looks up a page profile by name, with an empty name meaning the
+// default profile.
+func (h *Handler) Profile(name string) (types.PageProfile, bool) {
+	if name == "" {
+		name = types.DefaultProfile
 	}
+	profile, ok := h.Config.Profiles[name]
+	return profile, ok
+}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(jsonBody))
-	if err != nil {
-		response := types.Error("")
-		h.Log.Error("Error creating Hue API request.")
-		h.Notifier.SendErrorNotification("[PageHandler] Error creating Hue API request.")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		return
+// runPage pages the profile and reports the outcome through log, the
+// notifier and the audit log, naming source as the origin of any
+// notification.
+func (h *Handler) runPage(log *logrus.Entry, source string, profile types.PageProfile) types.Response {
+	log = log.WithField(targetField, profile.GroupedLightID)
+	entry := auditEntry(log, source, ActionPage, profile)
+	duration := time.Duration(profile.DurationMS) * time.Millisecond
+	release := func() {}
+	if cooldown := time.Duration(h.Config.PageCooldownSecs) * time.Second; cooldown > 0 {
+		now := time.Now()
+		var wait time.Duration
+		if release, wait = h.cooldowns.tryStart(profile.GroupedLightID, profile.Name, now, now.Add(min(cooldown, duration))); wait > 0 {
+			log.Infof("Coalesced page for profile %s into the page already running on %s", profile.Name, profile.GroupedLightID)
+			response := types.Failure(types.ErrorCoalesced, fmt.Sprintf("a page is already running on %s, coalesced into it", profile.GroupedLightID))
+			response.RetryAfter = wait
+			h.record(log, entry, response)
+			return response
+		}
 	}
 
-	req.Header.Add("hue-application-key", h.Config.HueUsername)
-	req.Header.Set("Content-Type", "application/json")
+	if err := h.page(profile); err != nil {
+		release()
+		h.reportBridgeError(log, source, err)
+		response := BridgeFailure(err)
+		h.record(log, entry, response)
+		return response
+	}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		response := types.Error("")
-		h.Log.Error("Error sending Hue API the request.")
-		h.Notifier.SendErrorNotification("[PageHandler] Error sending Hue API the request.")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		return
+	log.Infof("Successfully sent page for profile %s to Hue Bridge.", profile.Name)
+	entry.BridgeStatus = http.StatusOK
+	h.record(log, entry, types.Success())
+	h.pages.started(profile.Name, callerSource(log, source), duration)
+	if resolver, ok := h.Notifier.(types.Resolver); ok {
+		resolver.Resolve()
 	}
-	defer resp.Body.Close()
+	return types.Success()
+}
 
-	_, err = io.ReadAll(resp.Body)
-	if err != nil {
-		response := types.Error("")
-		h.Log.Error("Failed to parse Hue API JSON response.")
-		h.Notifier.SendErrorNotification("[PageHandler] Failed to parse Hue API JSON response.")
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		return
+// runCancel cancels any page running on the profile's lights and reports
+// the outcome like runPage does.
+func (h *Handler) runCancel(log *logrus.Entry, source string, profile types.PageProfile) types.Response {
+	log = log.WithField(targetField, profile.GroupedLightID)
+	entry := auditEntry(log, source, ActionCancel, profile)
+	if err := h.cancel(profile); err != nil {
+		h.reportBridgeError(log, source, err)
+		response := BridgeFailure(err)
+		h.record(log, entry, response)
+		return response
 	}
 
-	var response types.Response
+	log.Infof("Successfully cancelled page for profile %s on Hue Bridge.", profile.Name)
+	entry.BridgeStatus = http.StatusOK
+	h.record(log, entry, types.Success())
+	h.pages.stopped(profile.Name, callerSource(log, source))
+	h.cooldowns.stopped(profile.GroupedLightID)
+	return types.Success()
+}
+
+// CancelActivePages cancels every page that is still running, naming
+// source as the origin of any notification.
+func (h *Handler) CancelActivePages(source string) types.Response {
+	var commands []Command
+	for _, page := range h.ActivePages() {
+		commands = append(commands, Command{Action: ActionCancel, Profile: page.Profile})
+	}
+	return h.RunCommands(source, commands)
+}
 
-	if resp.StatusCode == 200 {
-		response = types.Success()
-		h.Log.Info("Successfully sent command to Hue Bridge.")
+func (h *Handler) reportBridgeError(log *logrus.Entry, source string, err error) {
+	if errors.Is(err, errBridgeNotConfigured) {
+		log.Warn("Environment variables are not properly set.")
 	} else {
-		response = types.Error("")
-		h.Log.Warnf("Received non-200 status code from Hue Bridge: %d", resp.StatusCode)
-		h.Notifier.SendErrorNotification(fmt.Sprintf("[PageHandler] Received non-200 status code from Hue Bridge: %d", resp.StatusCode))
+		log.Error(err)
+	}
+	h.notify(log, source, types.NotificationEvent{Level: types.LogLevelError, Status: types.NotificationFiring, Message: err.Error()})
+}
+
+// AllowMethods answers requests using any other method than the given
+// ones with a 405, unless HTTP_COMPAT_MODE is set. Allowing GET also
+// allows HEAD.
+func (h *Handler) AllowMethods(next http.HandlerFunc, methods ...string) http.HandlerFunc {
+	if slices.Contains(methods, http.MethodGet) {
+		methods = append(methods, http.MethodHead)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.Config.HTTPCompatMode || slices.Contains(methods, r.Method) {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		writeJSON(w, types.Failure(types.ErrorMethodNotAllowed, "method not allowed"))
+	}
+}
+
+// writeResponse answers with the response's status code, or with a 200 if
+// HTTP_COMPAT_MODE is set and the response doesn't ask the caller to come
+// back later.
+func (h *Handler) writeResponse(w http.ResponseWriter, response types.Response) {
+	if h.Config.HTTPCompatMode && response.RetryAfter == 0 {
+		response.StatusCode = http.StatusOK
+	}
+	writeJSON(w, response)
+}
+
+// writeJSON answers with the response's status code, falling back to 200
+// for successes and 500 for errors, and its Retry-After, if any. The body
+// carries the request ID the RequestID middleware put on the response
+// headers.
+func writeJSON(w http.ResponseWriter, response types.Response) {
+	if response.RetryAfter > 0 {
+		w.Header().Set("Retry-After", retryAfter(response.RetryAfter))
+	}
+	if response.RequestID == "" {
+		response.RequestID = w.Header().Get(RequestIDHeader)
+	}
+	statusCode := response.StatusCode
+	if statusCode == 0 && response.Status == types.Success().Status {
+		statusCode = http.StatusOK
+	} else if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// bridgeCall is a single signaling request received by the fake bridge.
+type bridgeCall struct {
+	GroupedLightID string
+	Signal         string
+}
+
+// fakeBridge records the signaling calls made against it and answers
+// with StatusCode after Delay.
+type fakeBridge struct {
+	*httptest.Server
+	StatusCode int
+	Delay      time.Duration
+
+	mu    sync.Mutex
+	calls []bridgeCall
+}
+
+func newFakeBridge(t *testing.T) *fakeBridge {
+	bridge := &fakeBridge{StatusCode: http.StatusOK}
+	bridge.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "user123", r.Header.Get("hue-application-key"))
+
+		var body struct {
+			Signaling struct {
+				Signal string `json:"signal"`
+			} `json:"signaling"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		bridge.mu.Lock()
+		bridge.calls = append(bridge.calls, bridgeCall{
+			GroupedLightID: strings.TrimPrefix(r.URL.Path, "/clip/v2/resource/grouped_light/"),
+			Signal:         body.Signaling.Signal,
+		})
+		bridge.mu.Unlock()
+
+		time.Sleep(bridge.Delay)
+		w.WriteHeader(bridge.StatusCode)
+	}))
+	t.Cleanup(bridge.Close)
+	return bridge
+}
+
+func (b *fakeBridge) Calls() []bridgeCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]bridgeCall(nil), b.calls...)
+}
+
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []string
+	events   []types.NotificationEvent
+	err      error
+}
+
+func (n *recordingNotifier) SendErrorNotification(message string) error {
+	return n.SendNotification(types.LogLevelError, message)
+}
+
+func (n *recordingNotifier) SendNotification(level types.LogLevel, message string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, message)
+	return n.err
+}
+
+func (n *recordingNotifier) SendEvent(event types.NotificationEvent) error {
+	n.mu.Lock()
+	n.events = append(n.events, event)
+	n.mu.Unlock()
+	return n.SendNotification(event.Level, event.String())
+}
+
+func newTestHandler(bridge *fakeBridge) (*Handler, *recordingNotifier) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	cfg := &types.Config{
+		BridgeAddress:  strings.TrimPrefix(bridge.URL, "https://"),
+		GroupedLightID: "group1",
+		HueUsername:    "user123",
+		DurationMS:     15000,
+		Profiles: map[string]types.PageProfile{
+			types.DefaultProfile: {Name: types.DefaultProfile, GroupedLightID: "group1", DurationMS: 15000},
+			"critical":           {Name: "critical", GroupedLightID: "office", DurationMS: 30000},
+		},
+	}
+	cfg.AlertmanagerProfile = types.DefaultProfile
+
+	notifier := &recordingNotifier{}
+	return NewHandler(cfg, log, notifier), notifier
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder) types.Response {
+	var response types.Response
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	return response
+}
+
+func TestPageHandler(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, notifier := newTestHandler(bridge)
+
+	rec := httptest.NewRecorder()
+	handler.PageHandler(rec, httptest.NewRequest("GET", "/page?profile=critical", nil))
+
+	assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+	assert.Equal(t, []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}}, bridge.Calls())
+	assert.Empty(t, notifier.messages)
+}
+
+func TestPageHandler_BridgeError(t *testing.T) {
+	bridge := newFakeBridge(t)
+	bridge.StatusCode = http.StatusForbidden
+	handler, notifier := newTestHandler(bridge)
+
+	rec := httptest.NewRecorder()
+	handler.PageHandler(rec, httptest.NewRequest("GET", "/page", nil))
+
+	assert.Equal(t, "broke", decodeResponse(t, rec).Status)
+	assert.Len(t, notifier.messages, 1)
+	assert.Contains(t, notifier.messages[0], "[PageHandler] received non-200 status code from Hue Bridge: 403")
+}
+
+func TestCancelHandler_UnknownProfile(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+
+	rec := httptest.NewRecorder()
+	handler.CancelHandler(rec, httptest.NewRequest("GET", "/cancel?profile=nope", nil))
+
+	assert.Equal(t, "broke", decodeResponse(t, rec).Status)
+	assert.Empty(t, bridge.Calls())
+}
+
+func TestActivePages(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.Profiles["short"] = types.PageProfile{Name: "short", GroupedLightID: "group1", DurationMS: 20}
+
+	events, unsubscribe := handler.SubscribePages()
+	defer unsubscribe()
+
+	handler.PageHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/page?profile=critical", nil))
+	handler.PageHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/page?profile=short", nil))
+
+	active := handler.ActivePages()
+	assert.Len(t, active, 2)
+	assert.Equal(t, "critical", active[0].Profile)
+	assert.Equal(t, "PageHandler", active[0].Source)
+
+	handler.CancelHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/cancel?profile=critical", nil))
+
+	expected := []PageEvent{
+		{Profile: "critical", Active: true, Source: "PageHandler"},
+		{Profile: "short", Active: true, Source: "PageHandler"},
+		{Profile: "critical", Active: false, Source: "CancelHandler"},
+		{Profile: "short", Active: false, Source: "PageHandler"},
+	}
+	for _, want := range expected {
+		select {
+		case event := <-events:
+			event.Time = time.Time{}
+			assert.Equal(t, want, event)
+		case <-time.After(time.Second):
+			t.Fatalf("missing event %+v", want)
+		}
+	}
+	assert.Empty(t, handler.ActivePages())
+}
+
+func TestCancelActivePages(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+
+	handler.PageHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/page?profile=critical", nil))
+	handler.PageHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/page", nil))
+
+	assert.Equal(t, "okay", handler.CancelActivePages("shutdown").Status)
+	assert.Empty(t, handler.ActivePages())
+	assert.ElementsMatch(t, []bridgeCall{
+		{GroupedLightID: "office", Signal: "alternating"},
+		{GroupedLightID: "group1", Signal: "alternating"},
+		{GroupedLightID: "office", Signal: "no_signal"},
+		{GroupedLightID: "group1", Signal: "no_signal"},
+	}, bridge.Calls())
+}
+
+func TestAllowMethods(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	page := handler.AllowMethods(handler.PageHandler, http.MethodPost)
+	ping := handler.AllowMethods(handler.PingHandler, http.MethodGet)
+
+	rec := httptest.NewRecorder()
+	page(rec, httptest.NewRequest("GET", "/page", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "POST", rec.Header().Get("Allow"))
+	assert.Equal(t, "method not allowed", decodeResponse(t, rec).Message)
+
+	rec = httptest.NewRecorder()
+	ping(rec, httptest.NewRequest("HEAD", "/ping", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	handler.Config.HTTPCompatMode = true
+	rec = httptest.NewRecorder()
+	page(rec, httptest.NewRequest("GET", "/page", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, bridge.Calls(), 1)
+}
+
+func TestStatusCodes(t *testing.T) {
+	tests := []struct {
+		description string
+		configure   func(handler *Handler, bridge *fakeBridge)
+		url         string
+		statusCode  int
+	}{
+		{"unknown profile", func(*Handler, *fakeBridge) {}, "/page?profile=nope", http.StatusBadRequest},
+		{"bridge rejected", func(_ *Handler, bridge *fakeBridge) { bridge.StatusCode = http.StatusForbidden }, "/page", http.StatusBadGateway},
+		{"misconfigured", func(handler *Handler, _ *fakeBridge) { handler.Config.HueUsername = "" }, "/page", http.StatusServiceUnavailable},
+		{"compat mode", func(handler *Handler, _ *fakeBridge) { handler.Config.HTTPCompatMode = true }, "/page?profile=nope", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, _ := newTestHandler(bridge)
+			test.configure(handler, bridge)
+
+			rec := httptest.NewRecorder()
+			handler.PageHandler(rec, httptest.NewRequest("POST", test.url, nil))
+			assert.Equal(t, test.statusCode, rec.Code)
+			assert.Equal(t, "broke", decodeResponse(t, rec).Status)
+		})
+	}
+}
+
+func TestPageHandler_BridgeErrorDetails(t *testing.T) {
+	bridge := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":[{"description":"unauthorized user"}],"data":[]}`))
+	}))
+	defer bridge.Close()
+
+	handler, _ := newTestHandler(&fakeBridge{Server: bridge})
+
+	rec := httptest.NewRecorder()
+	handler.PageHandler(rec, httptest.NewRequest("POST", "/page", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, types.Response{
+		Status:  "broke",
+		Code:    types.ErrorBridgeRejected,
+		Message: "the Hue Bridge rejected the request",
+		Details: &types.ErrorDetails{BridgeStatus: http.StatusForbidden, BridgeErrors: []string{"unauthorized user"}},
+	}, decodeResponse(t, rec))
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+var errBridgeNotConfigured = errors.New("environment variables are not properly set")
+
+// custom http client that ignores certificate verification
+// this is some shit that the hue api imposes on us
+var hueClient = &http.Client{
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+	Timeout: 10 * time.Second,
+}
+
+// bridgeError is a failed call to the bridge, classified by what went
+// wrong.
+type bridgeError struct {
+	Code         types.ErrorCode
+	StatusCode   int
+	Descriptions []string
+	Err          error
+}
+
+func (e *bridgeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *bridgeError) Unwrap() error {
+	return e.Err
+}
+
+var bridgeErrorMessages = map[types.ErrorCode]string{
+	types.ErrorConfigMissing:     "the Hue Bridge settings are not configured",
+	types.ErrorConfigInvalid:     "the Hue Bridge address is invalid",
+	types.ErrorMarshalFailed:     "failed to build the Hue Bridge request",
+	types.ErrorBridgeUnreachable: "the Hue Bridge could not be reached",
+	types.ErrorBridgeTimeout:     "the Hue Bridge did not answer in time",
+	types.ErrorBridgeRejected:    "the Hue Bridge rejected the request",
+}
+
+// BridgeFailure converts an error from talking to the bridge into an error
+// response saying what went wrong.
+func BridgeFailure(err error) types.Response {
+	if errors.Is(err, errBridgeNotConfigured) {
+		return types.Failure(types.ErrorConfigMissing, bridgeErrorMessages[types.ErrorConfigMissing])
+	}
+
+	var bridgeErr *bridgeError
+	if !errors.As(err, &bridgeErr) {
+		return types.Failure(types.ErrorBridgeUnreachable, bridgeErrorMessages[types.ErrorBridgeUnreachable])
+	}
+
+	response := types.Failure(bridgeErr.Code, bridgeErrorMessages[bridgeErr.Code])
+	if bridgeErr.StatusCode != 0 {
+		response.Details = &types.ErrorDetails{BridgeStatus: bridgeErr.StatusCode, BridgeErrors: bridgeErr.Descriptions}
+	}
+	return response
+}
+
+// page makes the profile's lights alternate between its two colors.
+func (h *Handler) page(profile types.PageProfile) error {
+	return h.sendSignaling(profile.GroupedLightID, map[string]interface{}{
+		"signal":   "alternating",
+		"duration": profile.DurationMS,
+		"colors": []map[string]interface{}{
+			{"xy": profile.StartColorXY},
+			{"xy": profile.JumpColorXY},
+		},
+	})
+}
+
+// cancel stops any signal running on the profile's lights.
+func (h *Handler) cancel(profile types.PageProfile) error {
+	return h.sendSignaling(profile.GroupedLightID, map[string]interface{}{
+		"signal":   "no_signal",
+		"duration": 0,
+	})
+}
+
+// sendSignaling PUTs the signaling object to a grouped light on the bridge.
+func (h *Handler) sendSignaling(groupedLightID string, signaling map[string]interface{}) error {
+	if h.Config.BridgeAddress == "" || groupedLightID == "" || h.Config.HueUsername == "" {
+		return errBridgeNotConfigured
+	}
+
+	url := "https://" + h.Config.BridgeAddress + "/clip/v2/resource/grouped_light/" + groupedLightID
+
+	jsonBody, err := json.Marshal(map[string]interface{}{"signaling": signaling})
+	if err != nil {
+		return &bridgeError{Code: types.ErrorMarshalFailed, Err: fmt.Errorf("failed to stringify Hue API JSON request body: %w", err)}
+	}
+
+	req, err := http.NewRequest("PUT", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return &bridgeError{Code: types.ErrorConfigInvalid, Err: fmt.Errorf("error creating Hue API request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = h.doBridgeRequest(req)
+	return err
+}
+
+// doBridgeRequest sends req to the bridge with our application key and
+// returns the body of a 200 response.
+func (h *Handler) doBridgeRequest(req *http.Request) ([]byte, error) {
+	req.Header.Add("hue-application-key", h.Config.HueUsername)
+
+	resp, err := hueClient.Do(req)
+	if err != nil {
+		code := types.ErrorBridgeUnreachable
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			code = types.ErrorBridgeTimeout
+		}
+		return nil, &bridgeError{Code: code, Err: fmt.Errorf("error sending Hue API the request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &bridgeError{Code: types.ErrorBridgeUnreachable, Err: fmt.Errorf("failed to parse Hue API JSON response: %w", err)}
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, &bridgeError{
+			Code:         types.ErrorBridgeRejected,
+			StatusCode:   resp.StatusCode,
+			Descriptions: bridgeErrorDescriptions(body),
+			Err:          fmt.Errorf("received non-200 status code from Hue Bridge: %d", resp.StatusCode),
+		}
+	}
+
+	return body, nil
+}
+
+// bridgeErrorDescriptions pulls the error descriptions out of a Hue API v2
+// response body, if it has any.
+func bridgeErrorDescriptions(body []byte) []string {
+	var response struct {
+		Errors []struct {
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+
+	var descriptions []string
+	for _, e := range response.Errors {
+		descriptions = append(descriptions, e.Description)
+	}
+	return descriptions
+}
+
+// Group is a room or zone on the bridge together with the grouped light
+// that controls all of its lights.
+type Group struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	GroupedLightID string `json:"grouped_light_id"`
+}
+
+// CheckBridge verifies that the bridge is reachable and accepts our
+// application key.
+func (h *Handler) CheckBridge() error {
+	return h.getResource("bridge", nil)
+}
+
+// Groups lists the rooms and zones on the bridge, rooms first.
+func (h *Handler) Groups() ([]Group, error) {
+	var groups []Group
+	for _, resourceType := range []string{"room", "zone"} {
+		var body struct {
+			Data []struct {
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Metadata struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+				Services []struct {
+					RID   string `json:"rid"`
+					RType string `json:"rtype"`
+				} `json:"services"`
+			} `json:"data"`
+		}
+		if err := h.getResource(resourceType, &body); err != nil {
+			return nil, err
+		}
+
+		for _, resource := range body.Data {
+			group := Group{ID: resource.ID, Name: resource.Metadata.Name, Type: resource.Type}
+			for _, service := range resource.Services {
+				if service.RType == "grouped_light" {
+					group.GroupedLightID = service.RID
+				}
+			}
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+// getResource GETs a resource type from the bridge, decoding the response
+// into out unless it is nil.
+func (h *Handler) getResource(resourceType string, out interface{}) error {
+	if h.Config.BridgeAddress == "" || h.Config.HueUsername == "" {
+		return errBridgeNotConfigured
+	}
+
+	req, err := http.NewRequest("GET", "https://"+h.Config.BridgeAddress+"/clip/v2/resource/"+resourceType, nil)
+	if err != nil {
+		return &bridgeError{Code: types.ErrorConfigInvalid, Err: fmt.Errorf("error creating Hue API request: %w", err)}
+	}
+
+	body, err := h.doBridgeRequest(req)
+	if err != nil || out == nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return &bridgeError{Code: types.ErrorBridgeRejected, Err: fmt.Errorf("failed to parse Hue API JSON response: %w", err)}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errNotConfigured    = errors.New("integration is not configured")
+	errInvalidSignature = errors.New("invalid signature")
+	errInvalidPayload   = errors.New("invalid payload")
+)
+
+// maxWebhookBody is the largest webhook body IntegrationHandler reads.
+const maxWebhookBody = 1 << 20
+
+// Action is what a Command asks huproxy to do with a profile.
+type Action int
+
+const (
+	ActionPage Action = iota
+	ActionCancel
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionPage:
+		return "page"
+	case ActionCancel:
+		return "cancel"
+	default:
+		return "unknown"
+	}
+}
+
+// Command is the vendor independent outcome of an inbound webhook.
+type Command struct {
+	Action  Action
+	Profile string
+	// Target replaces the profile's grouped light ID, if set.
+	Target string
+	// Reason is sent to the notifiers once the command succeeds, if set.
+	Reason string
+}
+
+// Adapter converts the webhook payload of one inbound integration into
+// commands. Adapters are served on /integrations/<Name>.
+type Adapter interface {
+	Name() string
+	Parse(r *http.Request, body []byte) ([]Command, error)
+}
+
+// SignedAdapter is implemented by adapters that verify the vendor's own
+// webhook signature, so they don't need an API key in front of them.
+type SignedAdapter interface {
+	Adapter
+	VerifiesSignature() bool
+}
+
+// Integrations returns the adapters for every inbound integration.
+func (h *Handler) Integrations() []Adapter {
+	adapters := []Adapter{
+		&alertmanagerAdapter{config: h.Config},
+		&grafanaAdapter{config: h.Config},
+		&pagerDutyAdapter{config: h.Config},
+		&gitHubAdapter{config: h.Config},
+		&opsgenieAdapter{config: h.Config},
+	}
+	for _, webhook := range h.Config.GenericWebhooks {
+		adapters = append(adapters, &genericAdapter{webhook: webhook})
+	}
+	return adapters
+}
+
+// IntegrationHandler serves an Adapter, running the commands it produces
+// against the bridge.
+func (h *Handler) IntegrationHandler(adapter Adapter) http.HandlerFunc {
+	source := "integrations/" + adapter.Name()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := h.requestLog(r)
+		log.Infof("Received /%s request from %s", source, r.RemoteAddr)
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Warnf("Rejected %s webhook from %s: body is over %d bytes", adapter.Name(), r.RemoteAddr, maxWebhookBody)
+			h.writeResponse(w, types.Failure(types.ErrorPayloadTooLarge, "payload too large"))
+			return
+		} else if err != nil {
+			log.Warnf("Failed to read %s webhook body: %s", adapter.Name(), err)
+			h.writeResponse(w, types.Failure(types.ErrorInvalidPayload, errInvalidPayload.Error()))
+			return
+		}
+
+		commands, err := adapter.Parse(r, body)
+		if err != nil {
+			log.Warnf("Rejected %s webhook from %s: %s", adapter.Name(), r.RemoteAddr, err)
+			h.writeResponse(w, integrationFailure(err))
+			return
+		}
+
+		h.writeResponse(w, Acknowledge(h.runCommands(r.Context(), log, source, commands)))
+	}
+}
+
+// Acknowledge turns a coalesced page into a 202 success, for senders such
+// as vendor webhooks and MQTT that would take the failure for a broken
+// delivery and retry or give up on the subscription, rather than for the
+// page that is already running.
+func Acknowledge(response types.Response) types.Response {
+	if response.Code != types.ErrorCoalesced {
+		return response
+	}
+	return types.Response{Status: types.Success().Status, Message: response.Message, StatusCode: http.StatusAccepted}
+}
+
+// IntegrationRoute serves an Adapter behind Authenticated. Adapters that
+// verify their own signatures only get the client certificate checks and
+// rate limiting, since their vendors can't send an API key.
+func (h *Handler) IntegrationRoute(adapter Adapter) http.HandlerFunc {
+	next := h.IntegrationHandler(adapter)
+	if signed, ok := adapter.(SignedAdapter); ok && signed.VerifiesSignature() {
+		return h.VerifyClientCert(h.RateLimit(next))
+	}
+	return h.Authenticated(next)
+}
+
+// RunCommands runs every command, returning an error response if any of
+// them failed. A coalesced page is only reported if nothing else failed.
+func (h *Handler) RunCommands(source string, commands []Command) types.Response {
+	return h.runCommands(context.Background(), logrus.NewEntry(h.Log), source, commands)
+}
+
+// RunCommandsContext runs every command like RunCommands, on behalf of the
+// caller authenticated in ctx, if any, refusing the profiles and targets it
+// may not use.
+func (h *Handler) RunCommandsContext(ctx context.Context, source string, commands []Command) types.Response {
+	log := logrus.NewEntry(h.Log)
+	if peer, ok := ctx.Value(peerKey{}).(Peer); ok {
+		log = peerLog(log, peer)
+	}
+	return h.runCommands(ctx, contextLog(ctx, log), source, commands)
+}
+
+func (h *Handler) runCommands(ctx context.Context, log *logrus.Entry, source string, commands []Command) types.Response {
+	response := types.Success()
+	for _, command := range commands {
+		profile, ok := h.Profile(command.Profile)
+		if !ok {
+			log.Warnf("Unknown page profile routed: %s", command.Profile)
+			response = types.Failure(types.ErrorUnknownProfile, "unknown profile")
+			continue
+		}
+		if command.Target != "" {
+			profile.GroupedLightID = command.Target
+		}
+		if caller, ok := CallerFromContext(ctx); ok && !caller.Allows(profile) {
+			log.Warnf("Caller %s is not allowed to use profile %s", caller.Name, profile.Name)
+			response = types.Failure(types.ErrorForbidden, "profile not allowed")
+			continue
+		}
+
+		var result types.Response
+		if command.Action == ActionPage {
+			result = h.runPage(log, source, profile)
+		} else {
+			result = h.runCancel(log, source, profile)
+		}
+		if result.Status != types.Success().Status {
+			if result.Code != types.ErrorCoalesced || response.Status == types.Success().Status {
+				response = result
+			}
+			continue
+		}
+
+		if command.Reason != "" {
+			h.notify(log.WithField(targetField, profile.GroupedLightID), source, types.NotificationEvent{Level: types.LogLevelInfo, Status: types.NotificationInfo, Message: command.Reason})
+		}
+	}
+	return response
+}
+
+// routeProfile returns the profile of the first route matching labels, or
+// fallback if none match.
+func routeProfile(routes []types.LabelRoute, labels map[string]string, fallback string) string {
+	for _, route := range routes {
+		if route.Matches(labels) {
+			return route.Profile
+		}
+	}
+	return fallback
+}
+
+// integrationFailure is the error response to reject a webhook with,
+// without giving away more than which check failed.
+func integrationFailure(err error) types.Response {
+	switch {
+	case errors.Is(err, errNotConfigured):
+		return types.Failure(types.ErrorIntegrationNotConfigured, errNotConfigured.Error())
+	case errors.Is(err, errInvalidSignature):
+		return types.Failure(types.ErrorInvalidSignature, errInvalidSignature.Error())
+	default:
+		return types.Failure(types.ErrorInvalidPayload, errInvalidPayload.Error())
+	}
+}
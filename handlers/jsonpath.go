@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lookupJSONPath evaluates a small subset of JSONPath against a decoded JSON
+// document: a leading $ followed by .field and [index] steps, such as
+// $.incident.labels.team or $.alerts[0].status. Scalars are returned in
+// their string form.
+func lookupJSONPath(document interface{}, path string) (string, bool) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+
+	current := document
+	for path != "" {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return "", false
+			}
+			if current, ok = object[path[:end]]; !ok {
+				return "", false
+			}
+			path = path[end:]
+		case '[':
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return "", false
+			}
+			index, err := strconv.Atoi(path[1:end])
+			array, ok := current.([]interface{})
+			if err != nil || !ok || index < 0 || index >= len(array) {
+				return "", false
+			}
+			current = array[index]
+			path = path[end+1:]
+		default:
+			return "", false
+		}
+	}
+
+	switch value := current.(type) {
+	case string:
+		return value, true
+	case float64, bool:
+		return fmt.Sprint(value), true
+	default:
+		return "", false
+	}
+}
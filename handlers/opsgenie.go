@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// opsgeniePayload is the subset of an Opsgenie outgoing webhook payload
+// that huproxy cares about.
+type opsgeniePayload struct {
+	Action string `json:"action"`
+	Alert  struct {
+		AlertID  string   `json:"alertId"`
+		Message  string   `json:"message"`
+		Priority string   `json:"priority"`
+		Entity   string   `json:"entity"`
+		Source   string   `json:"source"`
+		Teams    []string `json:"teams"`
+	} `json:"alert"`
+}
+
+// opsgenieAdapter handles Opsgenie outgoing webhooks. Created alerts page
+// the profile picked by OPSGENIE_ROUTES, which can match the priority,
+// entity, source and team labels, and acknowledged or closed alerts cancel
+// it.
+type opsgenieAdapter struct {
+	config *types.Config
+}
+
+func (a *opsgenieAdapter) Name() string {
+	return "opsgenie"
+}
+
+func (a *opsgenieAdapter) Parse(r *http.Request, body []byte) ([]Command, error) {
+	var payload opsgeniePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidPayload, err)
+	}
+
+	labels := map[string]string{
+		"priority": payload.Alert.Priority,
+		"entity":   payload.Alert.Entity,
+		"source":   payload.Alert.Source,
+	}
+	if len(payload.Alert.Teams) > 0 {
+		labels["team"] = payload.Alert.Teams[0]
+	}
+	profile := routeProfile(a.config.OpsgenieRoutes, labels, a.config.OpsgenieProfile)
+	if profile == types.NoProfile {
+		return nil, nil
+	}
+
+	switch payload.Action {
+	case "Create":
+		return []Command{{Action: ActionPage, Profile: profile}}, nil
+	case "Acknowledge", "Close":
+		return []Command{{Action: ActionCancel, Profile: profile}}, nil
+	default:
+		return nil, nil
+	}
+}
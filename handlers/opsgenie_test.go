@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpsgenieAdapter(t *testing.T) {
+	tests := []struct {
+		description    string
+		defaultProfile string
+		payload        string
+		expectedCalls  []bridgeCall
+	}{
+		{
+			description:   "created P1 alert pages the routed profile",
+			payload:       `{"action":"Create","alert":{"alertId":"a1","message":"DB down","priority":"P1","teams":["infra"]}}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}},
+		},
+		{
+			description:   "closed P1 alert cancels the routed profile",
+			payload:       `{"action":"Close","alert":{"alertId":"a1","priority":"P1"}}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "office", Signal: "no_signal"}},
+		},
+		{
+			description:   "created P3 alert pages the default profile",
+			payload:       `{"action":"Create","alert":{"alertId":"a2","priority":"P3"}}`,
+			expectedCalls: []bridgeCall{{GroupedLightID: "group1", Signal: "alternating"}},
+		},
+		{
+			description:    "created P3 alert is dropped without a default profile",
+			defaultProfile: types.NoProfile,
+			payload:        `{"action":"Create","alert":{"alertId":"a2","priority":"P3"}}`,
+		},
+		{
+			description: "other actions are ignored",
+			payload:     `{"action":"AddNote","alert":{"alertId":"a1","priority":"P1"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, _ := newTestHandler(bridge)
+			handler.Config.OpsgenieProfile = types.DefaultProfile
+			if test.defaultProfile != "" {
+				handler.Config.OpsgenieProfile = test.defaultProfile
+			}
+			handler.Config.OpsgenieRoutes = []types.LabelRoute{
+				{Matchers: map[string]string{"priority": "P1"}, Profile: "critical"},
+			}
+
+			rec := httptest.NewRecorder()
+			handler.IntegrationHandler(&opsgenieAdapter{config: handler.Config})(rec, httptest.NewRequest("POST", "/integrations/opsgenie", strings.NewReader(test.payload)))
+
+			assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+			assert.Equal(t, test.expectedCalls, bridge.Calls())
+		})
+	}
+}
+
+func TestOpsgenieAdapter_BodyTooLarge(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+
+	payload := `{"action":"Create","alert":{"message":"` + strings.Repeat("a", maxWebhookBody) + `"}}`
+	rec := httptest.NewRecorder()
+	handler.IntegrationHandler(&opsgenieAdapter{config: handler.Config})(rec, httptest.NewRequest("POST", "/integrations/opsgenie", strings.NewReader(payload)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, types.ErrorPayloadTooLarge, decodeResponse(t, rec).Code)
+	assert.Empty(t, bridge.Calls())
+}
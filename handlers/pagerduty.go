@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// pagerDutyPayload is the subset of a PagerDuty V3 webhook payload that
+// huproxy cares about.
+type pagerDutyPayload struct {
+	Event struct {
+		ID        string `json:"id"`
+		EventType string `json:"event_type"`
+		Data      struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"data"`
+	} `json:"event"`
+}
+
+// pagerDutyAdapter handles PagerDuty V3 webhooks. Triggered incidents page
+// PAGERDUTY_PROFILE, escalated incidents page PAGERDUTY_ESCALATION_PROFILE
+// and acknowledged or resolved incidents cancel both.
+type pagerDutyAdapter struct {
+	config *types.Config
+}
+
+func (a *pagerDutyAdapter) Name() string {
+	return "pagerduty"
+}
+
+// VerifiesSignature is true, every webhook has to carry a valid
+// X-PagerDuty-Signature.
+func (a *pagerDutyAdapter) VerifiesSignature() bool {
+	return true
+}
+
+func (a *pagerDutyAdapter) Parse(r *http.Request, body []byte) ([]Command, error) {
+	if a.config.PagerDutySecret == "" {
+		return nil, fmt.Errorf("%w: PAGERDUTY_WEBHOOK_SECRET is not set", errNotConfigured)
+	}
+
+	if !validPagerDutySignature(a.config.PagerDutySecret, body, r.Header.Get("X-PagerDuty-Signature")) {
+		return nil, errInvalidSignature
+	}
+
+	var payload pagerDutyPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidPayload, err)
+	}
+
+	incident := payload.Event.Data.Title
+	if incident == "" {
+		incident = payload.Event.Data.ID
+	}
+	reason := fmt.Sprintf("Handled %s for %s", payload.Event.EventType, incident)
+
+	switch payload.Event.EventType {
+	case "incident.triggered":
+		return []Command{{Action: ActionPage, Profile: a.config.PagerDutyProfile, Reason: reason}}, nil
+	case "incident.escalated":
+		return []Command{{Action: ActionPage, Profile: a.config.PagerDutyEscalation, Reason: reason}}, nil
+	case "incident.acknowledged", "incident.resolved":
+		commands := []Command{{Action: ActionCancel, Profile: a.config.PagerDutyProfile, Reason: reason}}
+		if a.config.PagerDutyEscalation != a.config.PagerDutyProfile {
+			commands = append(commands, Command{Action: ActionCancel, Profile: a.config.PagerDutyEscalation})
+		}
+		return commands, nil
+	default:
+		return nil, nil
+	}
+}
+
+// validPagerDutySignature checks the X-PagerDuty-Signature header, which
+// holds one or more comma separated v1=<hex> signatures while secrets are
+// being rotated.
+func validPagerDutySignature(secret string, body []byte, header string) bool {
+	for _, signature := range strings.Split(header, ",") {
+		signature = strings.TrimSpace(signature)
+		if !strings.HasPrefix(signature, "v1=") {
+			continue
+		}
+		if validHMACSHA256(secret, body, strings.TrimPrefix(signature, "v1=")) {
+			return true
+		}
+	}
+	return false
+}
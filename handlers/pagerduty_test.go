@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func signPagerDuty(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestPagerDutyHandler(t *testing.T) {
+	tests := []struct {
+		description   string
+		eventType     string
+		expectedCalls []bridgeCall
+	}{
+		{"triggered pages the profile", "incident.triggered", []bridgeCall{{GroupedLightID: "group1", Signal: "alternating"}}},
+		{"escalated pages the escalation profile", "incident.escalated", []bridgeCall{{GroupedLightID: "office", Signal: "alternating"}}},
+		{"other events are ignored", "incident.annotated", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, notifier := newTestHandler(bridge)
+			handler.Config.PagerDutySecret = "secret"
+			handler.Config.PagerDutyProfile = "default"
+			handler.Config.PagerDutyEscalation = "critical"
+
+			body := `{"event":{"id":"01","event_type":"` + test.eventType + `","data":{"id":"P1","title":"Database down"}}}`
+			req := httptest.NewRequest("POST", "/integrations/pagerduty", strings.NewReader(body))
+			req.Header.Set("X-PagerDuty-Signature", "v1=deadbeef, "+signPagerDuty("secret", body))
+
+			rec := httptest.NewRecorder()
+			handler.IntegrationHandler(&pagerDutyAdapter{config: handler.Config})(rec, req)
+
+			assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+			assert.Equal(t, test.expectedCalls, bridge.Calls())
+			if test.expectedCalls != nil {
+				assert.Contains(t, notifier.messages[0], "Database down")
+				assert.Equal(t, "integrations/pagerduty", notifier.events[0].Handler)
+				assert.Equal(t, types.NotificationInfo, notifier.events[0].Status)
+				assert.Equal(t, test.expectedCalls[0].GroupedLightID, notifier.events[0].Target)
+			}
+		})
+	}
+}
+
+func TestPagerDutyHandler_ResolvedCancelsBothProfiles(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.PagerDutySecret = "secret"
+	handler.Config.PagerDutyProfile = "default"
+	handler.Config.PagerDutyEscalation = "critical"
+
+	body := `{"event":{"event_type":"incident.resolved","data":{"id":"P1"}}}`
+	req := httptest.NewRequest("POST", "/integrations/pagerduty", strings.NewReader(body))
+	req.Header.Set("X-PagerDuty-Signature", signPagerDuty("secret", body))
+
+	rec := httptest.NewRecorder()
+	handler.IntegrationHandler(&pagerDutyAdapter{config: handler.Config})(rec, req)
+
+	assert.Equal(t, "okay", decodeResponse(t, rec).Status)
+	assert.ElementsMatch(t, []bridgeCall{
+		{GroupedLightID: "group1", Signal: "no_signal"},
+		{GroupedLightID: "office", Signal: "no_signal"},
+	}, bridge.Calls())
+}
+
+func TestPagerDutyHandler_InvalidSignature(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.PagerDutySecret = "secret"
+
+	body := `{"event":{"event_type":"incident.triggered"}}`
+	req := httptest.NewRequest("POST", "/integrations/pagerduty", strings.NewReader(body))
+	req.Header.Set("X-PagerDuty-Signature", signPagerDuty("wrong", body))
+
+	rec := httptest.NewRecorder()
+	handler.IntegrationHandler(&pagerDutyAdapter{config: handler.Config})(rec, req)
+
+	response := decodeResponse(t, rec)
+	assert.Equal(t, "broke", response.Status)
+	assert.Equal(t, "invalid signature", response.Message)
+	assert.Empty(t, bridge.Calls())
+}
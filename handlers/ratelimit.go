@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// RateLimit limits every caller, identified by API key name or remote
+// address, to RATE_LIMIT_PER_MINUTE requests with bursts of up to
+// RATE_LIMIT_BURST, answering 429 with a Retry-After once they run out. It
+// has to run after the auth middlewares to see the API key.
+func (h *Handler) RateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.limiter == nil {
+			next(w, r)
+			return
+		}
+
+		key := remoteHost(r)
+		if caller, ok := CallerFromContext(r.Context()); ok {
+			key = "key:" + caller.Name
+		}
+
+		if wait := h.Throttle(key); wait > 0 {
+			h.requestLog(r).Warnf("Rate limited %s request from %s", r.URL.Path, key)
+			response := types.Failure(types.ErrorRateLimited, fmt.Sprintf("rate limited, retry in %ss", retryAfter(wait)))
+			response.RetryAfter = wait
+			writeJSON(w, response)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Throttle takes a request from the rate limit of the caller identified by
+// key, returning how long to wait if it has run out. It never throttles
+// when rate limiting is disabled.
+func (h *Handler) Throttle(key string) time.Duration {
+	if h.limiter == nil {
+		return 0
+	}
+	return h.limiter.take(key, time.Now())
+}
+
+// rateLimiter is a token bucket per caller.
+type rateLimiter struct {
+	perSecond float64
+	burst     float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	return &rateLimiter{
+		perSecond: float64(perMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+	}
+}
+
+// take spends a token from the key's bucket, returning how long to wait
+// for the next one if the bucket is empty.
+func (l *rateLimiter) take(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// forget buckets that have filled back up
+	for other, b := range l.buckets {
+		if b.refill(now, l.perSecond, l.burst) >= l.burst && other != key {
+			delete(l.buckets, other)
+		}
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = b.refill(now, l.perSecond, l.burst)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+func (b *bucket) refill(now time.Time, perSecond, burst float64) float64 {
+	return math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+}
+
+// cooldownTracker remembers, per grouped light, which profile is paging it
+// and until when further pages of that profile are coalesced into it.
+type cooldownTracker struct {
+	mu   sync.Mutex
+	held map[string]cooldown
+}
+
+type cooldown struct {
+	profile string
+	until   time.Time
+}
+
+func newCooldownTracker() *cooldownTracker {
+	return &cooldownTracker{held: make(map[string]cooldown)}
+}
+
+// tryStart checks and reserves target for a page of profile until the
+// given time in one step, so a burst of pages can't all get through while
+// the first one is still talking to the bridge. If a page of the same
+// profile holds target, it reserves nothing and returns how much longer
+// that page holds it. A page of another profile takes target over. The
+// returned release hands target back to whatever held it before, for when
+// the page fails.
+func (c *cooldownTracker) tryStart(target, profile string, now, until time.Time) (release func(), wait time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, held := c.held[target]
+	held = held && now.Before(previous.until)
+	if held && previous.profile == profile {
+		return nil, previous.until.Sub(now)
+	}
+
+	reserved := cooldown{profile: profile, until: until}
+	c.held[target] = reserved
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.held[target] != reserved {
+			return
+		}
+		if held {
+			c.held[target] = previous
+		} else {
+			delete(c.held, target)
+		}
+	}, 0
+}
+
+func (c *cooldownTracker) stopped(target string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.held, target)
+}
+
+// retryAfter rounds wait up to whole seconds for a Retry-After header.
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
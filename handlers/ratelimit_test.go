@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.limiter = newRateLimiter(60, 2)
+	ping := handler.RateLimit(handler.PingHandler)
+
+	codes := func(remoteAddr string, n int) []int {
+		var codes []int
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest("GET", "/ping", nil)
+			req.RemoteAddr = remoteAddr
+			rec := httptest.NewRecorder()
+			ping(rec, req)
+			codes = append(codes, rec.Code)
+			if rec.Code == http.StatusTooManyRequests {
+				assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+				assert.Equal(t, "rate limited, retry in 1s", decodeResponse(t, rec).Message)
+			}
+		}
+		return codes
+	}
+
+	assert.Equal(t, []int{200, 200, 429}, codes("10.0.0.1:1234", 3))
+	assert.Equal(t, []int{200}, codes("10.0.0.2:1234", 1))
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	limiter := newRateLimiter(60, 1)
+	now := time.Now()
+
+	assert.Zero(t, limiter.take("a", now))
+	assert.Equal(t, time.Second, limiter.take("a", now))
+	assert.Equal(t, 500*time.Millisecond, limiter.take("a", now.Add(500*time.Millisecond)))
+	assert.Zero(t, limiter.take("a", now.Add(time.Second)))
+}
+
+func TestPageCooldown(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.PageCooldownSecs = 60
+
+	page := func(profile string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.PageHandler(rec, httptest.NewRequest("GET", "/page?profile="+profile, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, page("critical").Code)
+
+	rec := page("critical")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "a page is already running on office, coalesced into it", decodeResponse(t, rec).Message)
+
+	response := handler.RunCommands("integrations/test", []Command{{Action: ActionPage, Profile: "critical"}})
+	assert.Equal(t, types.ErrorCoalesced, response.Code)
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, 30*time.Second, response.RetryAfter.Round(time.Second))
+
+	rec = httptest.NewRecorder()
+	payload := `{"version":"4","status":"firing","alerts":[{"status":"firing","labels":{"severity":"critical"}}]}`
+	handler.Config.AlertmanagerRoutes = []types.LabelRoute{{Matchers: map[string]string{"severity": "critical"}, Profile: "critical"}}
+	handler.IntegrationHandler(&alertmanagerAdapter{config: handler.Config})(rec, httptest.NewRequest("POST", "/integrations/alertmanager", strings.NewReader(payload)))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, types.Success().Status, decodeResponse(t, rec).Status)
+
+	assert.Equal(t, http.StatusOK, page(types.DefaultProfile).Code)
+
+	handler.CancelHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/cancel?profile=critical", nil))
+	assert.Equal(t, http.StatusOK, page("critical").Code)
+
+	assert.Equal(t, []bridgeCall{
+		{GroupedLightID: "office", Signal: "alternating"},
+		{GroupedLightID: "group1", Signal: "alternating"},
+		{GroupedLightID: "office", Signal: "no_signal"},
+		{GroupedLightID: "office", Signal: "alternating"},
+	}, bridge.Calls())
+}
+
+func TestPageCooldown_Concurrent(t *testing.T) {
+	bridge := newFakeBridge(t)
+	bridge.Delay = 50 * time.Millisecond
+	handler, _ := newTestHandler(bridge)
+	handler.Config.PageCooldownSecs = 60
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.RunCommands("integrations/test", []Command{{Action: ActionPage, Profile: "critical"}})
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, bridge.Calls(), 1)
+}
+
+func TestPageCooldown_OtherProfiles(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, _ := newTestHandler(bridge)
+	handler.Config.PageCooldownSecs = 60
+	handler.Config.Profiles["escalated"] = types.PageProfile{Name: "escalated", GroupedLightID: "office", DurationMS: 30000}
+
+	page := func(profile string) types.Response {
+		return handler.RunCommands("integrations/test", []Command{{Action: ActionPage, Profile: profile}})
+	}
+
+	bridge.StatusCode = http.StatusForbidden
+	assert.Equal(t, types.ErrorBridgeRejected, page("critical").Code)
+	bridge.StatusCode = http.StatusOK
+	assert.Equal(t, types.Success(), page("critical"), "a failed page doesn't hold the lights")
+	assert.Equal(t, types.Success(), page("escalated"), "another profile replaces the running page")
+	assert.Equal(t, types.ErrorCoalesced, page("escalated").Code)
+	assert.Equal(t, types.Success(), page("critical"))
+
+	assert.Len(t, bridge.Calls(), 4)
+}
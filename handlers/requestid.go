@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader carries the ID that correlates a request with its log
+// lines, response and notifications.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDField     = "request_id"
+	callerField        = "caller"
+	targetField        = "target"
+	remoteAddrField    = "remote_addr"
+	certificateField   = "certificate"
+	maxRequestIDLength = 128
+)
+
+type logKey struct{}
+
+// RequestID gives every request an ID, taking the caller's X-Request-ID if
+// it is sensible or generating one otherwise. The ID is echoed back in the
+// X-Request-ID header and the response body, and the log entry carrying it
+// is passed on in the request context for the rest of the chain.
+func (h *Handler) RequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		log := h.Log.WithField(requestIDField, id)
+		next(w, r.WithContext(context.WithValue(r.Context(), logKey{}, log)))
+	}
+}
+
+// RequestIDFromContext returns the ID the RequestID middleware gave the
+// request, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	log, ok := ctx.Value(logKey{}).(*logrus.Entry)
+	if !ok {
+		return "", false
+	}
+	return entryRequestID(log)
+}
+
+// requestLog returns the log entry for the request, which carries its
+// request ID if the RequestID middleware ran, its remote address, the
+// subject of its verified client certificate and the authenticated caller,
+// if any.
+func (h *Handler) requestLog(r *http.Request) *logrus.Entry {
+	log, ok := r.Context().Value(logKey{}).(*logrus.Entry)
+	if !ok {
+		log = logrus.NewEntry(h.Log)
+	}
+	peer := Peer{Addr: r.RemoteAddr}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		peer.Certificate = r.TLS.VerifiedChains[0][0].Subject.String()
+	}
+	return contextLog(r.Context(), peerLog(log, peer))
+}
+
+// contextLog adds the caller authenticated in ctx, if any, to log.
+func contextLog(ctx context.Context, log *logrus.Entry) *logrus.Entry {
+	if caller, ok := CallerFromContext(ctx); ok {
+		log = log.WithField(callerField, caller.Name)
+	}
+	return log
+}
+
+// peerLog adds where a request came from to log.
+func peerLog(log *logrus.Entry, peer Peer) *logrus.Entry {
+	if peer.Addr != "" {
+		log = log.WithField(remoteAddrField, peer.Addr)
+	}
+	if peer.Certificate != "" {
+		log = log.WithField(certificateField, peer.Certificate)
+	}
+	return log
+}
+
+// notify sends the event to the notifiers as coming from source, along
+// with the caller, grouped light and request ID of the log entry. Failed
+// deliveries are logged, since the notifiers don't log every failure
+// themselves.
+func (h *Handler) notify(log *logrus.Entry, source string, event types.NotificationEvent) {
+	event.Time = time.Now()
+	event.Handler = source
+	event.Caller, _ = entryCaller(log)
+	event.Target, _ = log.Data[targetField].(string)
+	event.RequestID, _ = entryRequestID(log)
+	if err := types.SendEvent(h.Notifier, event); err != nil {
+		log.Error("Failed to send notification: ", err)
+	}
+}
+
+func entryRequestID(log *logrus.Entry) (string, bool) {
+	id, ok := log.Data[requestIDField].(string)
+	return id, ok
+}
+
+func entryPeer(log *logrus.Entry) Peer {
+	addr, _ := log.Data[remoteAddrField].(string)
+	certificate, _ := log.Data[certificateField].(string)
+	return Peer{Addr: addr, Certificate: certificate}
+}
+
+func entryCaller(log *logrus.Entry) (string, bool) {
+	caller, ok := log.Data[callerField].(string)
+	return caller, ok
+}
+
+// callerSource names the caller of the log entry, if any, alongside
+// source.
+func callerSource(log *logrus.Entry, source string) string {
+	if caller, ok := entryCaller(log); ok {
+		return source + " (" + caller + ")"
+	}
+	return source
+}
+
+// validRequestID only accepts IDs that are safe to put in logs and
+// notifications as they are.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		description string
+		header      string
+		keep        bool
+	}{
+		{"caller's ID is kept", "abc-123", true},
+		{"missing ID is generated", "", false},
+		{"unsafe ID is replaced", "abc\ndef", false},
+		{"overlong ID is replaced", strings.Repeat("a", 129), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			bridge := newFakeBridge(t)
+			handler, notifier := newTestHandler(bridge)
+			bridge.StatusCode = http.StatusForbidden
+
+			req := httptest.NewRequest("POST", "/page", nil)
+			if test.header != "" {
+				req.Header.Set(RequestIDHeader, test.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.RequestID(handler.PageHandler)(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if test.keep {
+				assert.Equal(t, test.header, id)
+			} else {
+				assert.True(t, validRequestID(id))
+				assert.NotEqual(t, test.header, id)
+			}
+			assert.Equal(t, id, decodeResponse(t, rec).RequestID)
+			assert.Len(t, notifier.messages, 1)
+			assert.True(t, strings.HasSuffix(notifier.messages[0], " (target group1) (request "+id+")"))
+		})
+	}
+}
+
+func TestNotify_LogsFailures(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, notifier := newTestHandler(bridge)
+	notifier.err = errors.New("ntfy responded with status code 500")
+	hook := test.NewLocal(handler.Log)
+
+	bridge.StatusCode = http.StatusForbidden
+	handler.PageHandler(httptest.NewRecorder(), httptest.NewRequest("POST", "/page", nil))
+
+	assert.Len(t, notifier.messages, 1)
+	assert.Equal(t, "Failed to send notification: ntfy responded with status code 500", hook.LastEntry().Message)
+}
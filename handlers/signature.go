@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// validHMACSHA256 reports whether signature is the hex encoded HMAC-SHA256
+// of body keyed with secret, comparing in constant time.
+func validHMACSHA256(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/signing"
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+var (
+	errMalformedSignature = errors.New("malformed signature header")
+	errStaleSignature     = errors.New("signature timestamp is too old or in the future")
+	errReplayedNonce      = errors.New("nonce was already used")
+)
+
+// maxSignedBody is the largest request body VerifySignature reads.
+const maxSignedBody = 1 << 20
+
+// VerifySignature authenticates requests carrying an X-Huproxy-Signature
+// header of the form key=<name>,t=<unix seconds>,nonce=<nonce>,v1=<hex>,
+// where v1 is the HMAC-SHA256 of signing.Payload keyed with the named API
+// key. Stale timestamps and reused nonces are rejected. Requests without
+// the header are passed on to next untouched, so another middleware can
+// authenticate them.
+func (h *Handler) VerifySignature(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get(signing.Header)
+		if header == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBody))
+		if err != nil {
+			h.requestLog(r).Warnf("Failed to read signed request body from %s: %s", r.RemoteAddr, err)
+			writeJSON(w, types.Failure(types.ErrorInvalidPayload, errInvalidPayload.Error()))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key, err := h.verifySignature(header, r, body, time.Now())
+		if err != nil {
+			h.requestLog(r).Warnf("Rejected signed %s request from %s: %s", r.URL.Path, r.RemoteAddr, err)
+			writeJSON(w, types.Failure(types.ErrorUnauthorized, "unauthorized"))
+			return
+		}
+
+		next(w, r.WithContext(WithCaller(r.Context(), key.Caller)))
+	}
+}
+
+func (h *Handler) verifySignature(header string, r *http.Request, body []byte, now time.Time) (types.APIKey, error) {
+	fields := map[string]string{}
+	for _, field := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return types.APIKey{}, errMalformedSignature
+		}
+		fields[name] = value
+	}
+	if fields["key"] == "" || fields["t"] == "" || fields["nonce"] == "" || fields["v1"] == "" {
+		return types.APIKey{}, errMalformedSignature
+	}
+
+	var key types.APIKey
+	found := false
+	for _, candidate := range h.Config.APIKeys {
+		if candidate.Name == fields["key"] && candidate.Accepts(types.AuthSignature) {
+			key, found = candidate, true
+		}
+	}
+	if !found {
+		return types.APIKey{}, fmt.Errorf("%w: unknown key %s", errInvalidSignature, fields["key"])
+	}
+
+	payload := signing.Payload(fields["t"], fields["nonce"], r.Method, r.URL.RequestURI(), body)
+	if !validHMACSHA256(key.Key, payload, fields["v1"]) {
+		return types.APIKey{}, errInvalidSignature
+	}
+
+	seconds, err := strconv.ParseInt(fields["t"], 10, 64)
+	if err != nil {
+		return types.APIKey{}, errMalformedSignature
+	}
+	maxAge := time.Duration(h.Config.SignatureMaxAgeSecs) * time.Second
+	if age := now.Sub(time.Unix(seconds, 0)); age > maxAge || age < -maxAge {
+		return types.APIKey{}, errStaleSignature
+	}
+
+	if !h.nonces.use(key.Name+"/"+fields["nonce"], now, 2*maxAge) {
+		return types.APIKey{}, errReplayedNonce
+	}
+
+	return key, nil
+}
+
+// nonceCache remembers the nonces of signed requests for long enough that
+// their timestamps would be rejected as stale by the time they're
+// forgotten.
+type nonceCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{seen: make(map[string]time.Time)}
+}
+
+// use records the nonce, reporting false if it was already recorded.
+func (c *nonceCache) use(nonce string, now time.Time, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for seen, expires := range c.seen {
+		if now.After(expires) {
+			delete(c.seen, seen)
+		}
+	}
+
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = now.Add(ttl)
+	return true
+}
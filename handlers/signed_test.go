@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/signing"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signRequest(req *http.Request, key, secret string, timestamp time.Time, nonce, body string) {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(signing.Payload(t, nonce, req.Method, req.URL.RequestURI(), []byte(body)))
+	req.Header.Set(signing.Header, fmt.Sprintf("key=%s,t=%s,nonce=%s,v1=%s", key, t, nonce, hex.EncodeToString(mac.Sum(nil))))
+}
+
+func TestVerifySignature(t *testing.T) {
+	bridge := newFakeBridge(t)
+	handler, notifier := newTestHandler(bridge)
+	handler.Config.SignatureMaxAgeSecs = 300
+	handler.Config.APIKeys = []types.APIKey{
+		{Caller: types.Caller{Name: "cron"}, Key: "secret1", Auth: types.AuthSignature},
+		{Caller: types.Caller{Name: "ci"}, Key: "secret2", Auth: types.AuthBearer},
+	}
+	bridge.StatusCode = http.StatusForbidden
+	page := handler.VerifySignature(handler.RequireAPIKey(handler.PageHandler))
+
+	tests := []struct {
+		description string
+		sign        func(req *http.Request)
+		statusCode  int
+	}{
+		{"valid", func(req *http.Request) { signRequest(req, "cron", "secret1", time.Now(), "n1", "body") }, http.StatusBadGateway},
+		{"replayed nonce", func(req *http.Request) { signRequest(req, "cron", "secret1", time.Now(), "n1", "body") }, http.StatusUnauthorized},
+		{"stale", func(req *http.Request) { signRequest(req, "cron", "secret1", time.Now().Add(-time.Hour), "n2", "body") }, http.StatusUnauthorized},
+		{"wrong secret", func(req *http.Request) { signRequest(req, "cron", "nope", time.Now(), "n3", "body") }, http.StatusUnauthorized},
+		{"tampered body", func(req *http.Request) { signRequest(req, "cron", "secret1", time.Now(), "n4", "other") }, http.StatusUnauthorized},
+		{"bearer only key", func(req *http.Request) { signRequest(req, "ci", "secret2", time.Now(), "n5", "body") }, http.StatusUnauthorized},
+		{"malformed", func(req *http.Request) { req.Header.Set(signing.Header, "v1=abc") }, http.StatusUnauthorized},
+		{"unsigned bearer", func(req *http.Request) { req.Header.Set("Authorization", "Bearer secret2") }, http.StatusBadGateway},
+		{"signature key as bearer", func(req *http.Request) { req.Header.Set("Authorization", "Bearer secret1") }, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/page?profile=critical", strings.NewReader("body"))
+			test.sign(req)
+			rec := httptest.NewRecorder()
+			page(rec, req)
+			assert.Equal(t, test.statusCode, rec.Code)
+		})
+	}
+
+	assert.Len(t, bridge.Calls(), 2)
+	assert.Contains(t, notifier.messages[0], "[PageHandler (cron)]")
+}
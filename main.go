@@ -1,17 +1,42 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/YashdalfTheGray/huproxy/audit"
+	"github.com/YashdalfTheGray/huproxy/cli"
 	"github.com/YashdalfTheGray/huproxy/config"
 	"github.com/YashdalfTheGray/huproxy/handlers"
+	"github.com/YashdalfTheGray/huproxy/mqtt"
+	"github.com/YashdalfTheGray/huproxy/rpc"
+	"github.com/YashdalfTheGray/huproxy/server"
+	"github.com/YashdalfTheGray/huproxy/types"
 	"github.com/YashdalfTheGray/huproxy/utils"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		runCLI(os.Args[1:])
+		return
+	}
+
 	log := logrus.New()
 	log.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
@@ -27,15 +52,236 @@ func main() {
 		log.Fatal("Failed to load configuration: ", err)
 	}
 
-	discordNotifier := utils.NewDiscordNotifier(cfg, log)
+	if cfg.ErrorDiscordWebhookUrl != "" {
+		if err := utils.NewDiscordNotifier(cfg, log).Validate(); err != nil {
+			log.Error("Discord webhook failed validation, error notifications may not be delivered: ", err)
+		}
+	}
+
+	handler, err := newHandler(cfg, log)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ping := handler.PingHandler
+	if cfg.PingRequiresAuth {
+		ping = handler.Authenticated(ping)
+	}
+
+	// route serves next on pattern for the given methods, tagging every
+	// request with a request ID first. Routes go on their own mux so
+	// packages registering on http.DefaultServeMux, like expvar, don't end
+	// up served without auth.
+	mux := http.NewServeMux()
+	route := func(pattern string, next http.HandlerFunc, methods ...string) {
+		mux.HandleFunc(pattern, handler.RequestID(handler.AllowMethods(next, methods...)))
+	}
+
+	route("/ping", ping, http.MethodGet)
+	route("/page", handler.Authenticated(handler.PageHandler), http.MethodPost)
+	route("/cancel", handler.Authenticated(handler.CancelHandler), http.MethodPost)
+	route("/audit", handler.Authenticated(handler.AuditHandler), http.MethodGet)
+	route("/debug/vars", handler.Authenticated(expvar.Handler().ServeHTTP), http.MethodGet)
+	for _, adapter := range handler.Integrations() {
+		route("/integrations/"+adapter.Name(), handler.IntegrationRoute(adapter), http.MethodPost)
+	}
+
+	var mqttClient *mqtt.Client
+	if cfg.MQTTBrokerURL != "" {
+		mqttClient = mqtt.NewClient(cfg, log, handler)
+		if err := mqttClient.Start(); err != nil {
+			log.Error("Failed to connect to MQTT broker, MQTT is disabled: ", err)
+			mqttClient = nil
+		}
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.TLSCertFile != "" {
+		tlsConfig, err = server.NewTLSConfig(cfg, log)
+		if err != nil {
+			log.Fatal("Failed to set up TLS: ", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	serveErrs := make(chan error, 2)
+
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != 0 {
+		grpcAddress := net.JoinHostPort(cfg.GRPCListenAddress, strconv.Itoa(cfg.GRPCPort))
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC: ", err)
+		}
+		var opts []grpc.ServerOption
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		grpcServer = rpc.NewServer(log, handler).Register(opts...)
+		go func() {
+			log.Infof("Starting gRPC server on %s", grpcAddress)
+			if err := grpcServer.Serve(listener); err != nil {
+				serveErrs <- fmt.Errorf("gRPC server failed: %w", err)
+			}
+		}()
+	}
+
+	listener, err := server.Listen(cfg, log)
+	if err != nil {
+		log.Fatal("Failed to listen: ", err)
+	}
+
+	httpServer := server.New(mux, tlsConfig)
+	go func() {
+		var err error
+		if tlsConfig != nil {
+			log.Infof("Starting TLS server on %s", listener.Addr())
+			err = httpServer.ServeTLS(listener, "", "")
+		} else {
+			log.Infof("Starting server on %s", listener.Addr())
+			err = httpServer.Serve(listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			serveErrs <- fmt.Errorf("server failed: %w", err)
+		}
+	}()
+
+	failed := false
+	select {
+	case err := <-serveErrs:
+		log.Error(err)
+		failed = true
+	case <-ctx.Done():
+		log.Info("Shutting down, waiting for in-flight requests to finish")
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSecs)*time.Second)
+	defer cancel()
+	shutdown(shutdownCtx, log, cfg, handler, httpServer, grpcServer, mqttClient)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// shutdown drains in-flight HTTP and gRPC requests, optionally cancels the
+// pages still running, disconnects from MQTT, sends any notifications held
+// back and closes the audit log, giving up on draining once ctx is done.
+func shutdown(ctx context.Context, log *logrus.Logger, cfg *types.Config, handler *handlers.Handler, httpServer *http.Server, grpcServer *grpc.Server, mqttClient *mqtt.Client) {
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Error("Failed to drain in-flight requests: ", err)
+	}
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+	}
+
+	if cfg.CancelPagesOnShutdown {
+		if response := handler.CancelActivePages("shutdown"); response.Status != types.Success().Status {
+			log.Error("Failed to cancel running pages on shutdown")
+		}
+	}
+
+	if mqttClient != nil {
+		mqttClient.Stop()
+	}
+
+	if flusher, ok := handler.Notifier.(types.Flusher); ok {
+		if err := flusher.Flush(); err != nil {
+			log.Error("Failed to send held back notifications: ", err)
+		}
+	}
+
+	if handler.Audit != nil {
+		if err := handler.Audit.Close(); err != nil {
+			log.Error("Failed to close audit log: ", err)
+		}
+	}
+
+	log.Info("Shut down")
+}
+
+// newHandler builds the Handler with every configured notifier and the
+// audit log, if one is set.
+func newHandler(cfg *types.Config, log *logrus.Logger) (*handlers.Handler, error) {
+	handler := handlers.NewHandler(cfg, log, newNotifier(cfg, log))
+	if cfg.AuditLogFile != "" {
+		auditLog, err := audit.Open(cfg.AuditLogFile, int64(cfg.AuditLogMaxSizeMB)<<20, cfg.AuditLogMaxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		handler.Audit = auditLog
+	}
+	return handler, nil
+}
+
+// newNotifier builds the notifier for every configured backend, falling
+// back to Discord, wrapped in deduplication if it is enabled.
+func newNotifier(cfg *types.Config, log *logrus.Logger) types.Notifier {
+	var notifiers utils.MultiNotifier
+	if cfg.ErrorDiscordWebhookUrl != "" {
+		notifiers = append(notifiers, utils.NewDiscordNotifier(cfg, log))
+	}
+	if cfg.NtfyTopicURL != "" {
+		notifiers = append(notifiers, utils.NewNtfyNotifier(cfg, log))
+	}
+	if cfg.GotifyURL != "" {
+		notifiers = append(notifiers, utils.NewGotifyNotifier(cfg, log))
+	}
+	if cfg.SMTPHost != "" {
+		notifiers = append(notifiers, utils.NewEmailNotifier(cfg, log))
+	}
+	if cfg.WebhookURL != "" {
+		webhookNotifier, err := utils.NewWebhookNotifier(cfg, log)
+		if err != nil {
+			log.Fatal("Failed to set up webhook notifier: ", err)
+		}
+		notifiers = append(notifiers, webhookNotifier)
+	}
+	if len(notifiers) == 0 {
+		notifiers = append(notifiers, utils.NewDiscordNotifier(cfg, log))
+	}
+
+	var notifier types.Notifier = notifiers
+	if cfg.DedupWindowSeconds > 0 {
+		notifier = utils.NewDedupNotifier(notifier, time.Duration(cfg.DedupWindowSeconds)*time.Second, log)
+	}
+	return notifier
+}
 
-	handler := handlers.NewHandler(cfg, log, discordNotifier)
+// runCLI runs a client subcommand and exits non-zero if it failed.
+func runCLI(args []string) {
+	c := &cli.CLI{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Handler: func() (*handlers.Handler, error) {
+			log := logrus.New()
+			log.SetOutput(os.Stderr)
+			log.SetLevel(logrus.ErrorLevel)
 
-	http.HandleFunc("/ping", handler.PingHandler)
-	http.HandleFunc("/page", handler.PageHandler)
+			godotenv.Load()
+			cfg, err := config.LoadConfig(log)
+			if err != nil {
+				return nil, err
+			}
+			return newHandler(cfg, log)
+		},
+	}
 
-	log.Info("Starting server on :9090")
-	if err := http.ListenAndServe(":9090", nil); err != nil {
-		log.Fatal("Server failed: ", err)
+	if err := c.Run(args); err != nil {
+		if !errors.Is(err, cli.ErrFailed) && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,97 @@
+package mqtt
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// discoveryDevice groups every huproxy entity under one device in Home
+// Assistant.
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// discoveryConfig is the union of the Home Assistant MQTT discovery fields
+// used by the button, binary_sensor and sensor entities.
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	ObjectID          string          `json:"object_id,omitempty"`
+	Icon              string          `json:"icon,omitempty"`
+	CommandTopic      string          `json:"command_topic,omitempty"`
+	PayloadPress      string          `json:"payload_press,omitempty"`
+	StateTopic        string          `json:"state_topic,omitempty"`
+	ValueTemplate     string          `json:"value_template,omitempty"`
+	PayloadOn         string          `json:"payload_on,omitempty"`
+	PayloadOff        string          `json:"payload_off,omitempty"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	Device            discoveryDevice `json:"device"`
+}
+
+var unsafeObjectID = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// publishDiscovery publishes retained Home Assistant discovery configs for
+// a page button per profile, a page active binary sensor, a bridge health
+// sensor and a cancel button that cancels every running page.
+func (c *Client) publishDiscovery() {
+	nodeID := unsafeObjectID.ReplaceAllString(c.Config.MQTTClientID, "_")
+	device := discoveryDevice{
+		Identifiers:  []string{nodeID},
+		Name:         "huproxy",
+		Manufacturer: "huproxy",
+		Model:        "Hue pager",
+	}
+	entity := func(objectID, name string) discoveryConfig {
+		return discoveryConfig{
+			Name:              name,
+			UniqueID:          nodeID + "_" + objectID,
+			ObjectID:          nodeID + "_" + objectID,
+			AvailabilityTopic: c.topic("status"),
+			Device:            device,
+		}
+	}
+
+	profiles := make([]string, 0, len(c.Config.Profiles))
+	for name := range c.Config.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+
+	for _, profile := range profiles {
+		objectID := "page_" + strings.ToLower(unsafeObjectID.ReplaceAllString(profile, "_"))
+		button := entity(objectID, "Page "+profile)
+		button.Icon = "mdi:alarm-light"
+		button.CommandTopic = c.topic("page/" + profile)
+		c.publishJSON(c.discoveryTopic("button", nodeID, objectID), true, button)
+	}
+
+	cancel := entity("cancel", "Cancel page")
+	cancel.Icon = "mdi:alarm-light-off"
+	cancel.CommandTopic = c.topic("cancel")
+	cancel.PayloadPress = cancelAllPayload
+	c.publishJSON(c.discoveryTopic("button", nodeID, "cancel"), true, cancel)
+
+	active := entity("page_active", "Page active")
+	active.Icon = "mdi:alarm-light"
+	active.StateTopic = c.topic("status/active")
+	active.PayloadOn = "ON"
+	active.PayloadOff = "OFF"
+	active.DeviceClass = "running"
+	c.publishJSON(c.discoveryTopic("binary_sensor", nodeID, "page_active"), true, active)
+
+	health := entity("bridge_health", "Bridge health")
+	health.Icon = "mdi:bridge"
+	health.StateTopic = c.topic("status/bridge")
+	health.ValueTemplate = "{{ value_json.status }}"
+	c.publishJSON(c.discoveryTopic("sensor", nodeID, "bridge_health"), true, health)
+}
+
+// discoveryTopic builds <discovery prefix>/<component>/<node>/<object>/config.
+func (c *Client) discoveryTopic(component, nodeID, objectID string) string {
+	return strings.Join([]string{c.Config.HADiscoveryPrefix, component, nodeID, objectID, "config"}, "/")
+}
@@ -0,0 +1,252 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+)
+
+// PageResult is published to <prefix>/status/page after every page or
+// cancel triggered over MQTT.
+type PageResult struct {
+	Action  string    `json:"action"`
+	Profile string    `json:"profile"`
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// BridgeHealth is published, retained, to <prefix>/status/bridge every
+// health check interval.
+type BridgeHealth struct {
+	Status  string    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Client connects huproxy to an MQTT broker. It pages on messages to
+// <prefix>/page[/<profile>], cancels on <prefix>/cancel[/<profile>] and
+// publishes results, whether a page is active and bridge health under
+// <prefix>/status. It can also publish Home Assistant discovery configs.
+type Client struct {
+	Config  *types.Config
+	Log     *logrus.Logger
+	Handler *handlers.Handler
+
+	client      paho.Client
+	stop        chan struct{}
+	wg          sync.WaitGroup
+	unsubscribe func()
+}
+
+// NewClient creates a new Client with the given Config, Logger and Handler.
+func NewClient(config *types.Config, log *logrus.Logger, handler *handlers.Handler) *Client {
+	return &Client{
+		Config:  config,
+		Log:     log,
+		Handler: handler,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start connects to the broker and starts the bridge health checks. The
+// client keeps reconnecting and resubscribing in the background until
+// Stop is called.
+func (c *Client) Start() error {
+	opts := paho.NewClientOptions().
+		AddBroker(c.Config.MQTTBrokerURL).
+		SetClientID(c.Config.MQTTClientID).
+		SetUsername(c.Config.MQTTUsername).
+		SetPassword(c.Config.MQTTPassword).
+		SetAutoReconnect(true).
+		SetOrderMatters(false).
+		SetWill(c.topic("status"), "offline", 1, true).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			c.Log.Warn("Lost connection to MQTT broker: ", err)
+		})
+
+	c.client = paho.NewClient(opts)
+	if token := c.client.Connect(); token.Wait() && token.Error() != nil {
+		return token.Error()
+	}
+
+	events, unsubscribe := c.Handler.SubscribePages()
+	c.unsubscribe = unsubscribe
+
+	c.wg.Add(2)
+	go c.healthLoop()
+	go c.activeLoop(events)
+
+	return nil
+}
+
+// Stop marks huproxy offline, stops the health checks and disconnects.
+func (c *Client) Stop() {
+	close(c.stop)
+	c.wg.Wait()
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+	}
+
+	if c.client != nil && c.client.IsConnected() {
+		c.client.Publish(c.topic("status"), 1, true, "offline").WaitTimeout(time.Second)
+		c.client.Disconnect(250)
+	}
+}
+
+func (c *Client) onConnect(client paho.Client) {
+	c.Log.Infof("Connected to MQTT broker %s", c.Config.MQTTBrokerURL)
+
+	filters := map[string]byte{
+		c.topic("page"):     1,
+		c.topic("page/+"):   1,
+		c.topic("cancel"):   1,
+		c.topic("cancel/+"): 1,
+	}
+	if token := client.SubscribeMultiple(filters, c.onMessage); token.Wait() && token.Error() != nil {
+		c.Log.Error("Failed to subscribe to MQTT topics: ", token.Error())
+		return
+	}
+
+	if c.Config.HADiscovery {
+		c.publishDiscovery()
+	}
+	c.publishActive()
+	client.Publish(c.topic("status"), 1, true, "online")
+}
+
+// cancelAllPayload, sent to <prefix>/cancel, cancels every running page.
+const cancelAllPayload = `{"all":true}`
+
+// onMessage runs the page or cancel a message asks for. The profile comes
+// from the last topic level if there is one, otherwise from the payload,
+// which can be a plain profile name or {"profile": "<name>"}. A cancel with
+// a {"all": true} payload cancels every running page instead.
+func (c *Client) onMessage(_ paho.Client, message paho.Message) {
+	levels := strings.Split(strings.TrimPrefix(message.Topic(), c.Config.MQTTTopicPrefix+"/"), "/")
+
+	action := handlers.ActionPage
+	if levels[0] == "cancel" {
+		action = handlers.ActionCancel
+	}
+
+	if action == handlers.ActionCancel && len(levels) == 1 && cancelsAll(message.Payload()) {
+		c.Log.Infof("Received MQTT request to cancel every page on %s", message.Topic())
+		response := c.Handler.CancelActivePages("MQTT")
+		c.publishJSON(c.topic("status/page"), false, PageResult{
+			Action:  action.String(),
+			Status:  response.Status,
+			Message: response.Message,
+			Time:    time.Now(),
+		})
+		return
+	}
+
+	profile := ""
+	if len(levels) > 1 {
+		profile = levels[1]
+	} else {
+		profile = profileFromPayload(message.Payload())
+	}
+	if profile == "" {
+		profile = types.DefaultProfile
+	}
+
+	c.Log.Infof("Received MQTT %s request for profile %s on %s", action, profile, message.Topic())
+	response := handlers.Acknowledge(c.Handler.RunCommands("MQTT", []handlers.Command{{Action: action, Profile: profile}}))
+
+	c.publishJSON(c.topic("status/page"), false, PageResult{
+		Action:  action.String(),
+		Profile: profile,
+		Status:  response.Status,
+		Message: response.Message,
+		Time:    time.Now(),
+	})
+}
+
+func (c *Client) healthLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(c.Config.MQTTHealthIntervalSecs) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		c.publishHealth()
+		select {
+		case <-ticker.C:
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Client) publishHealth() {
+	health := BridgeHealth{Status: types.Success().Status, Time: time.Now()}
+	if err := c.Handler.CheckBridge(); err != nil {
+		health.Status = types.Error("").Status
+		health.Message = err.Error()
+	}
+	c.publishJSON(c.topic("status/bridge"), true, health)
+}
+
+func (c *Client) publishJSON(topic string, retained bool, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.Log.Error("Failed to marshal MQTT payload: ", err)
+		return
+	}
+	c.client.Publish(topic, 1, retained, body)
+}
+
+// publishActive publishes, retained, whether any page is running.
+func (c *Client) publishActive() {
+	state := "OFF"
+	if len(c.Handler.ActivePages()) > 0 {
+		state = "ON"
+	}
+	c.client.Publish(c.topic("status/active"), 1, true, state)
+}
+
+// activeLoop republishes the page active state whenever a page starts or
+// stops, whatever triggered it.
+func (c *Client) activeLoop(events <-chan handlers.PageEvent) {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-events:
+			c.publishActive()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Client) topic(subtopic string) string {
+	return c.Config.MQTTTopicPrefix + "/" + subtopic
+}
+
+func cancelsAll(payload []byte) bool {
+	var body struct {
+		All bool `json:"all"`
+	}
+	return json.Unmarshal(payload, &body) == nil && body.All
+}
+
+func profileFromPayload(payload []byte) string {
+	var body struct {
+		Profile string `json:"profile"`
+	}
+	if err := json.Unmarshal(payload, &body); err == nil {
+		return body.Profile
+	}
+	return strings.TrimSpace(string(payload))
+}
@@ -0,0 +1,255 @@
+package mqtt
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// startBroker runs an embedded MQTT broker on a free local port and
+// returns its URL.
+func startBroker(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	server := mochi.New(&mochi.Options{InlineClient: true})
+	server.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	assert.NoError(t, server.AddHook(new(auth.AllowHook), nil))
+	assert.NoError(t, server.AddListener(listeners.NewTCP(listeners.Config{ID: "tcp", Address: address})))
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	return "tcp://" + address
+}
+
+// startBridge runs a fake Hue bridge recording the signal of every
+// signaling request.
+func startBridge(t *testing.T) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var signals []string
+
+	bridge := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			var body struct {
+				Signaling struct {
+					Signal string `json:"signal"`
+				} `json:"signaling"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			mu.Lock()
+			signals = append(signals, strings.TrimPrefix(r.URL.Path, "/clip/v2/resource/grouped_light/")+":"+body.Signaling.Signal)
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(bridge.Close)
+
+	return bridge, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), signals...)
+	}
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) SendErrorNotification(message string) error                  { return nil }
+func (nopNotifier) SendNotification(level types.LogLevel, message string) error { return nil }
+
+func TestClient(t *testing.T) {
+	brokerURL := startBroker(t)
+	bridge, signals := startBridge(t)
+
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	cfg := &types.Config{
+		BridgeAddress: strings.TrimPrefix(bridge.URL, "https://"),
+		HueUsername:   "user123",
+		Profiles: map[string]types.PageProfile{
+			types.DefaultProfile: {Name: types.DefaultProfile, GroupedLightID: "group1"},
+			"critical":           {Name: "critical", GroupedLightID: "office"},
+		},
+		MQTTBrokerURL:          brokerURL,
+		MQTTClientID:           "huproxy-test",
+		MQTTTopicPrefix:        "huproxy",
+		MQTTHealthIntervalSecs: 60,
+	}
+	client := NewClient(cfg, log, handlers.NewHandler(cfg, log, nopNotifier{}))
+	assert.NoError(t, client.Start())
+	defer client.Stop()
+
+	results := make(chan PageResult, 10)
+	health := make(chan BridgeHealth, 10)
+	observer := paho.NewClient(paho.NewClientOptions().AddBroker(brokerURL).SetClientID("observer"))
+	token := observer.Connect()
+	token.Wait()
+	assert.NoError(t, token.Error())
+	defer observer.Disconnect(0)
+
+	filters := map[string]byte{"huproxy/status/page": 1, "huproxy/status/bridge": 1}
+	observer.SubscribeMultiple(filters, func(_ paho.Client, message paho.Message) {
+		if message.Topic() == "huproxy/status/bridge" {
+			var result BridgeHealth
+			json.Unmarshal(message.Payload(), &result)
+			health <- result
+			return
+		}
+		var result PageResult
+		json.Unmarshal(message.Payload(), &result)
+		results <- result
+	}).Wait()
+
+	select {
+	case status := <-health:
+		assert.Equal(t, "okay", status.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no bridge health published")
+	}
+
+	messages := []struct {
+		topic    string
+		payload  string
+		expected PageResult
+	}{
+		{"huproxy/page/critical", "", PageResult{Action: "page", Profile: "critical", Status: "okay"}},
+		{"huproxy/page", `{"profile":"critical"}`, PageResult{Action: "page", Profile: "critical", Status: "okay"}},
+		{"huproxy/cancel", "", PageResult{Action: "cancel", Profile: "default", Status: "okay"}},
+		{"huproxy/page/nope", "", PageResult{Action: "page", Profile: "nope", Status: "broke", Message: "unknown profile"}},
+	}
+	for _, message := range messages {
+		observer.Publish(message.topic, 1, false, message.payload).Wait()
+
+		select {
+		case result := <-results:
+			result.Time = time.Time{}
+			assert.Equal(t, message.expected, result, message.topic)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no result published for %s", message.topic)
+		}
+	}
+
+	assert.Equal(t, []string{"office:alternating", "office:alternating", "group1:no_signal"}, signals())
+}
+
+func TestClient_HomeAssistantDiscovery(t *testing.T) {
+	brokerURL := startBroker(t)
+	bridge, _ := startBridge(t)
+
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	cfg := &types.Config{
+		BridgeAddress: strings.TrimPrefix(bridge.URL, "https://"),
+		HueUsername:   "user123",
+		Profiles: map[string]types.PageProfile{
+			types.DefaultProfile: {Name: types.DefaultProfile, GroupedLightID: "group1", DurationMS: 60000},
+			"critical":           {Name: "critical", GroupedLightID: "office", DurationMS: 60000},
+		},
+		MQTTBrokerURL:          brokerURL,
+		MQTTClientID:           "huproxy-test",
+		MQTTTopicPrefix:        "huproxy",
+		MQTTHealthIntervalSecs: 60,
+		HADiscovery:            true,
+		HADiscoveryPrefix:      "homeassistant",
+	}
+	handler := handlers.NewHandler(cfg, log, nopNotifier{})
+	client := NewClient(cfg, log, handler)
+	assert.NoError(t, client.Start())
+	defer client.Stop()
+
+	var mu sync.Mutex
+	configs := map[string]map[string]interface{}{}
+	active := make(chan string, 10)
+
+	observer := paho.NewClient(paho.NewClientOptions().AddBroker(brokerURL).SetClientID("observer"))
+	token := observer.Connect()
+	token.Wait()
+	assert.NoError(t, token.Error())
+	defer observer.Disconnect(0)
+
+	filters := map[string]byte{"homeassistant/#": 1, "huproxy/status/active": 1}
+	observer.SubscribeMultiple(filters, func(_ paho.Client, message paho.Message) {
+		if message.Topic() == "huproxy/status/active" {
+			active <- string(message.Payload())
+			return
+		}
+		var config map[string]interface{}
+		json.Unmarshal(message.Payload(), &config)
+		mu.Lock()
+		configs[message.Topic()] = config
+		mu.Unlock()
+	}).Wait()
+
+	expectedTopics := []string{
+		"homeassistant/button/huproxy-test/page_default/config",
+		"homeassistant/button/huproxy-test/page_critical/config",
+		"homeassistant/button/huproxy-test/cancel/config",
+		"homeassistant/binary_sensor/huproxy-test/page_active/config",
+		"homeassistant/sensor/huproxy-test/bridge_health/config",
+	}
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(configs) == len(expectedTopics)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	for _, topic := range expectedTopics {
+		assert.Contains(t, configs, topic)
+	}
+	button := configs["homeassistant/button/huproxy-test/page_critical/config"]
+	assert.Equal(t, "huproxy/page/critical", button["command_topic"])
+	assert.Equal(t, "huproxy/status", button["availability_topic"])
+	sensor := configs["homeassistant/sensor/huproxy-test/bridge_health/config"]
+	assert.Equal(t, "huproxy/status/bridge", sensor["state_topic"])
+	cancel := configs["homeassistant/button/huproxy-test/cancel/config"]
+	mu.Unlock()
+
+	expectState := func(expected string) {
+		select {
+		case state := <-active:
+			assert.Equal(t, expected, state)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("page active state %s not published", expected)
+		}
+	}
+	expectState("OFF")
+
+	// Home Assistant sends PRESS unless the config sets payload_press
+	press := func(config map[string]interface{}) {
+		payload, ok := config["payload_press"].(string)
+		if !ok {
+			payload = "PRESS"
+		}
+		observer.Publish(config["command_topic"].(string), 1, false, payload).Wait()
+	}
+
+	press(button)
+	expectState("ON")
+
+	press(cancel)
+	expectState("OFF")
+}
@@ -0,0 +1,253 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: huproxy/v1/pager.proto
+
+package huproxyv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Pager_Page_FullMethodName       = "/huproxy.v1.Pager/Page"
+	Pager_Cancel_FullMethodName     = "/huproxy.v1.Pager/Cancel"
+	Pager_Status_FullMethodName     = "/huproxy.v1.Pager/Status"
+	Pager_WatchPages_FullMethodName = "/huproxy.v1.Pager/WatchPages"
+)
+
+// PagerClient is the client API for Pager service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Pager exposes the same pages as the HTTP API.
+type PagerClient interface {
+	// Page makes the lights of a profile alternate between its colors.
+	Page(ctx context.Context, in *PageRequest, opts ...grpc.CallOption) (*PageResponse, error)
+	// Cancel stops a running page.
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
+	// Status reports whether huproxy is configured, like /ping, along with
+	// the pages currently running.
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	// WatchPages streams an event whenever a page starts or stops.
+	WatchPages(ctx context.Context, in *WatchPagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PageEvent], error)
+}
+
+type pagerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPagerClient(cc grpc.ClientConnInterface) PagerClient {
+	return &pagerClient{cc}
+}
+
+func (c *pagerClient) Page(ctx context.Context, in *PageRequest, opts ...grpc.CallOption) (*PageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PageResponse)
+	err := c.cc.Invoke(ctx, Pager_Page_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pagerClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, Pager_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pagerClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, Pager_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pagerClient) WatchPages(ctx context.Context, in *WatchPagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[PageEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Pager_ServiceDesc.Streams[0], Pager_WatchPages_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchPagesRequest, PageEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pager_WatchPagesClient = grpc.ServerStreamingClient[PageEvent]
+
+// PagerServer is the server API for Pager service.
+// All implementations must embed UnimplementedPagerServer
+// for forward compatibility.
+//
+// Pager exposes the same pages as the HTTP API.
+type PagerServer interface {
+	// Page makes the lights of a profile alternate between its colors.
+	Page(context.Context, *PageRequest) (*PageResponse, error)
+	// Cancel stops a running page.
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
+	// Status reports whether huproxy is configured, like /ping, along with
+	// the pages currently running.
+	Status(context.Context, *StatusRequest) (*StatusResponse, error)
+	// WatchPages streams an event whenever a page starts or stops.
+	WatchPages(*WatchPagesRequest, grpc.ServerStreamingServer[PageEvent]) error
+	mustEmbedUnimplementedPagerServer()
+}
+
+// UnimplementedPagerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPagerServer struct{}
+
+func (UnimplementedPagerServer) Page(context.Context, *PageRequest) (*PageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Page not implemented")
+}
+func (UnimplementedPagerServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedPagerServer) Status(context.Context, *StatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedPagerServer) WatchPages(*WatchPagesRequest, grpc.ServerStreamingServer[PageEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPages not implemented")
+}
+func (UnimplementedPagerServer) mustEmbedUnimplementedPagerServer() {}
+func (UnimplementedPagerServer) testEmbeddedByValue()               {}
+
+// UnsafePagerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PagerServer will
+// result in compilation errors.
+type UnsafePagerServer interface {
+	mustEmbedUnimplementedPagerServer()
+}
+
+func RegisterPagerServer(s grpc.ServiceRegistrar, srv PagerServer) {
+	// If the following call pancis, it indicates UnimplementedPagerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Pager_ServiceDesc, srv)
+}
+
+func _Pager_Page_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PagerServer).Page(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pager_Page_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PagerServer).Page(ctx, req.(*PageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pager_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PagerServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pager_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PagerServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pager_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PagerServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pager_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PagerServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pager_WatchPages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PagerServer).WatchPages(m, &grpc.GenericServerStream[WatchPagesRequest, PageEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pager_WatchPagesServer = grpc.ServerStreamingServer[PageEvent]
+
+// Pager_ServiceDesc is the grpc.ServiceDesc for Pager service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Pager_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "huproxy.v1.Pager",
+	HandlerType: (*PagerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Page",
+			Handler:    _Pager_Page_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _Pager_Cancel_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Pager_Status_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPages",
+			Handler:       _Pager_WatchPages_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "huproxy/v1/pager.proto",
+}
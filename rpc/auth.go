@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	huproxyv1 "github.com/YashdalfTheGray/huproxy/proto/huproxy/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// unaryAuth authenticates and rate limits every unary call like the HTTP
+// control endpoints are. Status stays open unless PING_REQUIRES_AUTH is
+// set, just like /ping.
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod == huproxyv1.Pager_Status_FullMethodName && !s.Handler.Config.PingRequiresAuth {
+		return next(ctx, req)
+	}
+
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.throttle(ctx); err != nil {
+		return nil, err
+	}
+	return next(ctx, req)
+}
+
+// streamAuth authenticates every streaming call.
+func (s *Server) streamAuth(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+	if _, err := s.authenticate(stream.Context()); err != nil {
+		return err
+	}
+	return next(srv, stream)
+}
+
+// authenticate identifies the caller by its verified client certificate,
+// checked against TLS_CLIENTS, or by one of the API keys in the
+// x-api-key or authorization metadata. Calls pass without a caller when no
+// API keys are configured. The peer's address and certificate are kept in
+// the returned context for the audit log.
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		remote := handlers.Peer{Addr: p.Addr.String()}
+		var cert *x509.Certificate
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 && len(info.State.VerifiedChains[0]) > 0 {
+			cert = info.State.VerifiedChains[0][0]
+			remote.Certificate = cert.Subject.String()
+		}
+		ctx = handlers.WithPeer(ctx, remote)
+
+		if cert != nil {
+			caller, ok := s.Handler.CertCaller(cert)
+			if !ok {
+				s.Log.Warnf("Rejected gRPC client certificate %s from %s", cert.Subject.CommonName, p.Addr)
+				return ctx, status.Error(codes.PermissionDenied, "certificate not allowed")
+			}
+			return handlers.WithCaller(ctx, caller), nil
+		}
+	}
+
+	if len(s.Handler.Config.APIKeys) == 0 {
+		return ctx, nil
+	}
+
+	key, ok := s.Handler.APIKey(metadataAPIKey(ctx))
+	if !ok {
+		s.Log.Warn("Rejected unauthenticated gRPC call")
+		return ctx, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return handlers.WithCaller(ctx, key.Caller), nil
+}
+
+// throttle applies the caller's rate limit, keyed the same way as over
+// HTTP.
+func (s *Server) throttle(ctx context.Context) error {
+	key := ""
+	if caller, ok := handlers.CallerFromContext(ctx); ok {
+		key = "key:" + caller.Name
+	} else if p, ok := peer.FromContext(ctx); ok {
+		key = p.Addr.String()
+		if host, _, err := net.SplitHostPort(key); err == nil {
+			key = host
+		}
+	}
+
+	if wait := s.Handler.Throttle(key); wait > 0 {
+		s.Log.Warnf("Rate limited gRPC call from %s", key)
+		return status.Error(codes.ResourceExhausted, fmt.Sprintf("rate limited, retry in %.0fs", math.Ceil(wait.Seconds())))
+	}
+	return nil
+}
+
+func metadataAPIKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 && keys[0] != "" {
+		return keys[0]
+	}
+	for _, value := range md.Get("authorization") {
+		if scheme, token, ok := strings.Cut(value, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
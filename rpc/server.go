@@ -0,0 +1,136 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	huproxyv1 "github.com/YashdalfTheGray/huproxy/proto/huproxy/v1"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements the huproxy.v1.Pager gRPC service on top of the same
+// Handler that serves the HTTP API.
+type Server struct {
+	huproxyv1.UnimplementedPagerServer
+
+	Log     *logrus.Logger
+	Handler *handlers.Handler
+}
+
+// NewServer creates a new Server with the given Logger and Handler.
+func NewServer(log *logrus.Logger, handler *handlers.Handler) *Server {
+	return &Server{
+		Log:     log,
+		Handler: handler,
+	}
+}
+
+// Register creates a grpc.Server serving the Pager service behind the
+// same authentication and rate limiting as the HTTP API.
+func (s *Server) Register(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(s.unaryAuth), grpc.ChainStreamInterceptor(s.streamAuth))
+	grpcServer := grpc.NewServer(opts...)
+	huproxyv1.RegisterPagerServer(grpcServer, s)
+	return grpcServer
+}
+
+func (s *Server) Page(ctx context.Context, req *huproxyv1.PageRequest) (*huproxyv1.PageResponse, error) {
+	s.Log.Infof("Received gRPC Page request for profile %q", req.GetProfile())
+
+	response, err := s.run(ctx, handlers.ActionPage, req.GetProfile())
+	if err != nil {
+		return nil, err
+	}
+	return &huproxyv1.PageResponse{Status: response.Status, Message: response.Message}, nil
+}
+
+func (s *Server) Cancel(ctx context.Context, req *huproxyv1.CancelRequest) (*huproxyv1.CancelResponse, error) {
+	s.Log.Infof("Received gRPC Cancel request for profile %q", req.GetProfile())
+
+	response, err := s.run(ctx, handlers.ActionCancel, req.GetProfile())
+	if err != nil {
+		return nil, err
+	}
+	return &huproxyv1.CancelResponse{Status: response.Status, Message: response.Message}, nil
+}
+
+func (s *Server) Status(ctx context.Context, req *huproxyv1.StatusRequest) (*huproxyv1.StatusResponse, error) {
+	response := s.Handler.Ping("gRPC")
+
+	result := &huproxyv1.StatusResponse{Status: response.Status, Message: response.Message}
+	for _, page := range s.Handler.ActivePages() {
+		result.ActivePages = append(result.ActivePages, &huproxyv1.ActivePage{
+			Profile: page.Profile,
+			Source:  page.Source,
+			Started: timestamppb.New(page.Started),
+			Until:   timestamppb.New(page.Until),
+		})
+	}
+	return result, nil
+}
+
+// WatchPages streams page events until the client goes away.
+func (s *Server) WatchPages(req *huproxyv1.WatchPagesRequest, stream huproxyv1.Pager_WatchPagesServer) error {
+	events, unsubscribe := s.Handler.SubscribePages()
+	defer unsubscribe()
+
+	for {
+		select {
+		case event := <-events:
+			err := stream.Send(&huproxyv1.PageEvent{
+				Profile: event.Profile,
+				Active:  event.Active,
+				Source:  event.Source,
+				Time:    timestamppb.New(event.Time),
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// run validates the profile and runs the command, turning failures into
+// gRPC status errors.
+func (s *Server) run(ctx context.Context, action handlers.Action, profile string) (types.Response, error) {
+	if profile == "" {
+		profile = types.DefaultProfile
+	}
+	if _, ok := s.Handler.Profile(profile); !ok {
+		return types.Response{}, status.Errorf(codes.NotFound, "unknown profile %s", profile)
+	}
+
+	response := s.Handler.RunCommandsContext(ctx, "gRPC", []handlers.Command{{Action: action, Profile: profile}})
+	if response.Status != types.Success().Status {
+		return types.Response{}, status.Errorf(grpcCode(response.Code), "failed to %s profile %s: %s", action, profile, response.Message)
+	}
+	return response, nil
+}
+
+// grpcCode is the gRPC status code matching an error code.
+func grpcCode(code types.ErrorCode) codes.Code {
+	switch code {
+	case types.ErrorUnknownProfile:
+		return codes.NotFound
+	case types.ErrorUnauthorized:
+		return codes.Unauthenticated
+	case types.ErrorForbidden:
+		return codes.PermissionDenied
+	case types.ErrorConfigMissing, types.ErrorConfigInvalid:
+		return codes.FailedPrecondition
+	case types.ErrorBridgeTimeout:
+		return codes.DeadlineExceeded
+	case types.ErrorRateLimited, types.ErrorCoalesced:
+		return codes.ResourceExhausted
+	default:
+		return codes.Unavailable
+	}
+}
@@ -0,0 +1,171 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/handlers"
+	huproxyv1 "github.com/YashdalfTheGray/huproxy/proto/huproxy/v1"
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type nopNotifier struct{}
+
+func (nopNotifier) SendErrorNotification(message string) error                  { return nil }
+func (nopNotifier) SendNotification(level types.LogLevel, message string) error { return nil }
+
+// newTestClient serves a Server over an in-memory connection, backed by a
+// fake bridge answering with bridgeStatus, after applying any configure
+// funcs to the config.
+func newTestClient(t *testing.T, bridgeStatus int, configure ...func(*types.Config)) huproxyv1.PagerClient {
+	bridge := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(bridgeStatus)
+	}))
+	t.Cleanup(bridge.Close)
+
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	cfg := &types.Config{
+		BridgeAddress:  strings.TrimPrefix(bridge.URL, "https://"),
+		GroupedLightID: "group1",
+		HueUsername:    "user123",
+		Profiles: map[string]types.PageProfile{
+			types.DefaultProfile: {Name: types.DefaultProfile, GroupedLightID: "group1", DurationMS: 60000},
+			"critical":           {Name: "critical", GroupedLightID: "office", DurationMS: 60000},
+		},
+	}
+	for _, f := range configure {
+		f(cfg)
+	}
+	handler := handlers.NewHandler(cfg, log, nopNotifier{})
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := NewServer(log, handler).Register()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return huproxyv1.NewPagerClient(conn)
+}
+
+func TestServer_PageCancelStatus(t *testing.T) {
+	client := newTestClient(t, http.StatusOK)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	watch, err := client.WatchPages(ctx, &huproxyv1.WatchPagesRequest{})
+	assert.NoError(t, err)
+	// make sure the watch is subscribed before paging
+	_, err = client.Status(ctx, &huproxyv1.StatusRequest{})
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	page, err := client.Page(ctx, &huproxyv1.PageRequest{Profile: "critical"})
+	assert.NoError(t, err)
+	assert.Equal(t, "okay", page.GetStatus())
+
+	statusResponse, err := client.Status(ctx, &huproxyv1.StatusRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "okay", statusResponse.GetStatus())
+	assert.Len(t, statusResponse.GetActivePages(), 1)
+	assert.Equal(t, "critical", statusResponse.GetActivePages()[0].GetProfile())
+	assert.Equal(t, "gRPC", statusResponse.GetActivePages()[0].GetSource())
+
+	_, err = client.Cancel(ctx, &huproxyv1.CancelRequest{Profile: "critical"})
+	assert.NoError(t, err)
+
+	for _, active := range []bool{true, false} {
+		event, err := watch.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, "critical", event.GetProfile())
+		assert.Equal(t, active, event.GetActive())
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newTestClient(t, http.StatusOK).Page(ctx, &huproxyv1.PageRequest{Profile: "nope"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = newTestClient(t, http.StatusForbidden).Page(ctx, &huproxyv1.PageRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestServer_Auth(t *testing.T) {
+	client := newTestClient(t, http.StatusOK, func(cfg *types.Config) {
+		cfg.APIKeys = []types.APIKey{
+			{Caller: types.Caller{Name: "ci", Profiles: []string{types.DefaultProfile}}, Key: "secret", Auth: types.AuthAny},
+		}
+	})
+	ctx := context.Background()
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	_, err := client.Page(ctx, &huproxyv1.PageRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Page(metadata.AppendToOutgoingContext(ctx, "x-api-key", "nope"), &huproxyv1.PageRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Page(authed, &huproxyv1.PageRequest{Profile: "critical"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.Page(authed, &huproxyv1.PageRequest{})
+	assert.NoError(t, err)
+
+	stream, err := client.WatchPages(ctx, &huproxyv1.WatchPagesRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.Status(ctx, &huproxyv1.StatusRequest{})
+	assert.NoError(t, err)
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	client := newTestClient(t, http.StatusOK, func(cfg *types.Config) {
+		cfg.RateLimitPerMinute = 1
+		cfg.RateLimitBurst = 1
+	})
+	ctx := context.Background()
+
+	_, err := client.Cancel(ctx, &huproxyv1.CancelRequest{})
+	assert.NoError(t, err)
+	_, err = client.Cancel(ctx, &huproxyv1.CancelRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestServer_Coalesced(t *testing.T) {
+	client := newTestClient(t, http.StatusOK, func(cfg *types.Config) {
+		cfg.PageCooldownSecs = 60
+	})
+	ctx := context.Background()
+
+	_, err := client.Page(ctx, &huproxyv1.PageRequest{})
+	assert.NoError(t, err)
+	_, err = client.Page(ctx, &huproxyv1.PageRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// listenFDsStart is the first file descriptor systemd passes sockets on.
+var listenFDsStart = 3
+
+// Listen returns the listener to serve the HTTP API on: the socket passed
+// by systemd socket activation if there is one, otherwise a unix socket if
+// the listen address starts with unix:, otherwise a TCP socket.
+func Listen(config *types.Config, log *logrus.Logger) (net.Listener, error) {
+	listener, ok, err := systemdListener(log)
+	if ok || err != nil {
+		return listener, err
+	}
+
+	if path, ok := strings.CutPrefix(config.ListenAddress, "unix:"); ok {
+		return listenUnix(path, os.FileMode(config.ListenSocketMode))
+	}
+	return net.Listen("tcp", config.ListenAddress)
+}
+
+// systemdListener returns the first socket systemd passed to huproxy, if
+// it was started through socket activation.
+func systemdListener(log *logrus.Logger) (net.Listener, bool, error) {
+	fds := os.Getenv("LISTEN_FDS")
+	if fds == "" || os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, false, nil
+	}
+
+	// the sockets are ours alone, don't pass them on to child processes
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 1 {
+		return nil, true, fmt.Errorf("invalid LISTEN_FDS value %q", fds)
+	}
+	if count > 1 {
+		log.Warnf("systemd passed %d sockets, only serving on the first one", count)
+	}
+
+	file := os.NewFile(uintptr(listenFDsStart), "LISTEN_FD_"+strconv.Itoa(listenFDsStart))
+	defer file.Close()
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to use the socket passed by systemd: %w", err)
+	}
+	return listener, true, nil
+}
+
+// listenUnix listens on a unix socket at path, replacing a stale socket
+// left behind by a previous run, and sets its permissions to mode.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return listener, nil
+}
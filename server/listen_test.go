@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListen_Unix(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	path := filepath.Join(t.TempDir(), "huproxy.sock")
+	cfg := &types.Config{ListenAddress: "unix:" + path, ListenSocketMode: 0o600}
+
+	// a socket left behind by a run that didn't clean up
+	stale, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := Listen(cfg, log)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	assert.NoError(t, err)
+	conn.Close()
+
+	notSocket := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(notSocket, nil, 0o600))
+	_, err = Listen(&types.Config{ListenAddress: "unix:" + notSocket}, log)
+	assert.Error(t, err)
+}
+
+func TestListen_Systemd(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	activated, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer activated.Close()
+	file, err := activated.(*net.TCPListener).File()
+	assert.NoError(t, err)
+	defer file.Close()
+
+	listenFDsStart = int(file.Fd())
+	defer func() { listenFDsStart = 3 }()
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listener, err := Listen(&types.Config{ListenAddress: "127.0.0.1:0"}, log)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	assert.Equal(t, activated.Addr().String(), listener.Addr().String())
+	assert.Empty(t, os.Getenv("LISTEN_FDS"))
+}
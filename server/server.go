@@ -0,0 +1,23 @@
+// Package server sets up the listener and http.Server huproxy serves its
+// HTTP API with.
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// New returns the http.Server for the HTTP API, with timeouts that keep
+// slow or idle clients from tying up connections. The write timeout leaves
+// room for a slow bridge.
+func New(handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+	}
+}
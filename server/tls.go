@@ -0,0 +1,165 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reloadInterval is how often the certificate files are checked for
+// changes.
+var reloadInterval = 10 * time.Second
+
+// NewTLSConfig returns a TLS config serving the configured certificate,
+// verifying client certificates against the client CA bundle if one is
+// set. The files are reloaded whenever they change, so renewed
+// certificates are picked up without a restart.
+func NewTLSConfig(config *types.Config, log *logrus.Logger) (*tls.Config, error) {
+	reloader := &certReloader{
+		certFile:   config.TLSCertFile,
+		keyFile:    config.TLSKeyFile,
+		caFile:     config.TLSClientCAFile,
+		clientAuth: tls.RequireAndVerifyClientCert,
+		interval:   reloadInterval,
+		log:        log,
+	}
+	if config.TLSClientAuth == types.TLSClientAuthOptional {
+		reloader.clientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetCertificate:     reloader.certificate,
+		GetConfigForClient: reloader.configForClient,
+	}, nil
+}
+
+// certReloader keeps the certificate and client CA pool in sync with the
+// files they were loaded from.
+type certReloader struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+	interval   time.Duration
+	log        *logrus.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checked   time.Time
+}
+
+func (c *certReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now := time.Now(); now.Sub(c.checked) >= c.interval {
+		c.checked = now
+		if c.changed() {
+			if err := c.loadLocked(); err != nil {
+				c.log.Error("Failed to reload TLS certificates, keeping the old ones: ", err)
+			} else {
+				c.log.Info("Reloaded TLS certificates")
+			}
+		}
+	}
+
+	// the config returned here replaces the server's, so it has to
+	// offer HTTP/2 itself for gRPC and HTTP/2 clients
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*c.cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if c.clientCAs != nil {
+		config.ClientCAs = c.clientCAs
+		config.ClientAuth = c.clientAuth
+	}
+	return config, nil
+}
+
+func (c *certReloader) certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cert, nil
+}
+
+func (c *certReloader) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked = time.Now()
+	return c.loadLocked()
+}
+
+func (c *certReloader) loadLocked() error {
+	modTimes, err := c.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if c.caFile != "" {
+		pem, err := os.ReadFile(c.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("client CA bundle has no certificates")
+		}
+	}
+
+	c.cert = &cert
+	c.clientCAs = clientCAs
+	c.modTimes = modTimes
+	return nil
+}
+
+// changed reports whether any of the files were modified since they were
+// last loaded.
+func (c *certReloader) changed() bool {
+	modTimes, err := c.stat()
+	if err != nil {
+		c.log.Warn("Failed to check TLS certificates for changes: ", err)
+		return false
+	}
+	for file, modTime := range modTimes {
+		if !modTime.Equal(c.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *certReloader) stat() (map[string]time.Time, error) {
+	modTimes := map[string]time.Time{}
+	for _, file := range []string{c.certFile, c.keyFile, c.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return modTimes, nil
+}
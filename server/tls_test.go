@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCert is a certificate and key signed by parent, or self signed if
+// parent is nil.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	if keyFile != "" {
+		assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	reloadInterval = 0
+	defer func() { reloadInterval = 10 * time.Second }()
+
+	dir := t.TempDir()
+	cfg := &types.Config{
+		TLSCertFile:     filepath.Join(dir, "cert.pem"),
+		TLSKeyFile:      filepath.Join(dir, "key.pem"),
+		TLSClientCAFile: filepath.Join(dir, "ca.pem"),
+		TLSClientAuth:   types.TLSClientAuthRequire,
+	}
+
+	ca := newTestCert(t, "huproxy test CA", nil)
+	ca.write(t, cfg.TLSClientCAFile, "")
+	newTestCert(t, "first", ca).write(t, cfg.TLSCertFile, cfg.TLSKeyFile)
+	clientCert := newTestCert(t, "ci", ca)
+
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+	tlsConfig, err := NewTLSConfig(cfg, log)
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+	})}
+	go server.Serve(tls.NewListener(listener, tlsConfig))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	connect := func(certs ...tls.Certificate) (string, error) {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: roots, Certificates: certs})
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		// the server only checks the client certificate after the
+		// handshake, so make it read a request before reporting success
+		if _, err := conn.Write([]byte("GET / HTTP/1.0\r\n\r\n")); err != nil {
+			return "", err
+		}
+		buf := make([]byte, 1)
+		if _, err := conn.Read(buf); err != nil {
+			return "", err
+		}
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+	}
+
+	serverName, err := connect(clientCert.tlsCertificate())
+	assert.NoError(t, err)
+	assert.Equal(t, "first", serverName)
+
+	_, err = connect()
+	assert.Error(t, err, "client certificates are required")
+
+	newTestCert(t, "second", ca).write(t, cfg.TLSCertFile, cfg.TLSKeyFile)
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(cfg.TLSCertFile, future, future))
+
+	serverName, err = connect(clientCert.tlsCertificate())
+	assert.NoError(t, err)
+	assert.Equal(t, "second", serverName)
+}
@@ -0,0 +1,14 @@
+// Package signing defines the format of signed huproxy requests, shared by
+// the server and the client.
+package signing
+
+import "strings"
+
+// Header carries the signature of a signed request.
+const Header = "X-Huproxy-Signature"
+
+// Payload returns the bytes a signed request's signature covers.
+func Payload(timestamp, nonce, method, requestURI string, body []byte) []byte {
+	payload := []byte(strings.Join([]string{timestamp, nonce, method, requestURI}, "\n") + "\n")
+	return append(payload, body...)
+}
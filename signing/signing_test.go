@@ -0,0 +1,12 @@
+package signing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	assert.Equal(t, "1700000000\nabc\nPOST\n/page?profile=critical\n{}", string(Payload("1700000000", "abc", "POST", "/page?profile=critical", []byte("{}"))))
+	assert.Equal(t, "1700000000\nabc\nGET\n/ping\n", string(Payload("1700000000", "abc", "GET", "/ping", nil)))
+}
@@ -1,6 +1,13 @@
 package types
 
-import "github.com/YashdalfTheGray/huproxy/color"
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/color"
+)
 
 type LogLevel int
 
@@ -25,6 +32,145 @@ func (l LogLevel) String() string {
 
 type Notifier interface {
 	SendErrorNotification(message string) error
+	SendNotification(level LogLevel, message string) error
+}
+
+// Resolver is implemented by notifiers that track ongoing error conditions
+// and can announce when they clear.
+type Resolver interface {
+	Resolve() error
+}
+
+// Flusher is implemented by notifiers that hold notifications back and
+// need to send them before huproxy exits.
+type Flusher interface {
+	Flush() error
+}
+
+// EventSender is implemented by notifiers that take the fields of a
+// notification rather than just its text.
+type EventSender interface {
+	SendEvent(event NotificationEvent) error
+}
+
+// SendEvent sends the event through notifier, as an event if it is an
+// EventSender and as the event's text otherwise.
+func SendEvent(notifier Notifier, event NotificationEvent) error {
+	if sender, ok := notifier.(EventSender); ok {
+		return sender.SendEvent(event)
+	}
+	return notifier.SendNotification(event.Level, event.String())
+}
+
+// The statuses of a NotificationEvent.
+const (
+	// NotificationFiring is a failure, like the bridge rejecting a page.
+	NotificationFiring = "firing"
+	// NotificationResolved is a failure that has cleared.
+	NotificationResolved = "resolved"
+	// NotificationInfo is anything else, like a handled webhook.
+	NotificationInfo = "info"
+)
+
+// NotificationEvent describes a single notification.
+type NotificationEvent struct {
+	Level   LogLevel
+	Message string
+	Time    time.Time
+	// Handler is where the notification comes from, like PageHandler.
+	Handler string
+	// Caller is the API key or client certificate behind the
+	// notification, if any.
+	Caller string
+	// Target is the grouped light the notification is about, if any.
+	Target string
+	// Status is one of NotificationFiring, NotificationResolved or
+	// NotificationInfo.
+	Status string
+	// RequestID is the ID of the HTTP request that caused the
+	// notification, if any.
+	RequestID string
+}
+
+// MessageEvent is the event of a bare message sent through
+// SendNotification, which is firing unless it is only informational.
+func MessageEvent(level LogLevel, message string) NotificationEvent {
+	status := NotificationFiring
+	if level == LogLevelInfo {
+		status = NotificationInfo
+	}
+	return NotificationEvent{Level: level, Message: message, Time: time.Now(), Status: status}
+}
+
+// String renders the event as text for notifiers that only take messages,
+// like "[PageHandler (ci)] bridge down (target office) (request 4f2a)".
+func (e NotificationEvent) String() string {
+	var b strings.Builder
+	if e.Handler != "" {
+		b.WriteString("[" + e.Handler)
+		if e.Caller != "" {
+			b.WriteString(" (" + e.Caller + ")")
+		}
+		b.WriteString("] ")
+	}
+	b.WriteString(e.Message)
+	if e.Target != "" {
+		b.WriteString(" (target " + e.Target + ")")
+	}
+	if e.RequestID != "" {
+		b.WriteString(" (request " + e.RequestID + ")")
+	}
+	return b.String()
+}
+
+// Caller is an authenticated client of the control endpoints. Empty
+// Profiles or Targets allow any profile or grouped light.
+type Caller struct {
+	Name     string
+	Profiles []string
+	Targets  []string
+}
+
+// Allows reports whether the caller may page or cancel the profile.
+func (c Caller) Allows(profile PageProfile) bool {
+	if len(c.Profiles) > 0 && !slices.Contains(c.Profiles, profile.Name) {
+		return false
+	}
+	return len(c.Targets) == 0 || slices.Contains(c.Targets, profile.GroupedLightID)
+}
+
+// The ways an APIKey may be presented.
+const (
+	AuthAny       = "any"
+	AuthBearer    = "bearer"
+	AuthSignature = "signature"
+)
+
+// APIKey is a named key accepted by the control endpoints, either sent as
+// is or used as the secret of a signed request, as Auth allows.
+type APIKey struct {
+	Caller
+	Key  string
+	Auth string
+}
+
+// Accepts reports whether the key may be presented in the given way.
+func (k APIKey) Accepts(auth string) bool {
+	return k.Auth == AuthAny || k.Auth == auth
+}
+
+// The ways a client certificate may be asked for when a client CA bundle
+// is configured.
+const (
+	TLSClientAuthRequire  = "require"
+	TLSClientAuthOptional = "optional"
+)
+
+// TLSClient is a caller identified by a client certificate whose common
+// name or one of whose subject alternative names is in Names.
+type TLSClient struct {
+	Caller
+	Names []string
 }
 
 // Config holds the environment configuration.
@@ -38,12 +184,201 @@ type Config struct {
 	StartColorXY           color.XY
 	JumpColorXY            color.XY
 	DurationMS             int
+	DedupWindowSeconds     int
+	NtfyTopicURL           string
+	NtfyPriority           int
+	NtfyTags               []string
+	NtfyToken              string
+	GotifyURL              string
+	GotifyAppToken         string
+	GotifyPriority         int
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string
+	SMTPPassword           string
+	SMTPFrom               string
+	SMTPTo                 []string
+	SMTPTLSMode            string
+	WebhookURL             string
+	WebhookMethod          string
+	WebhookHeaders         []string
+	WebhookBody            string
+	Profiles               map[string]PageProfile
+	AlertmanagerRoutes     []LabelRoute
+	AlertmanagerProfile    string
+	GrafanaRoutes          []LabelRoute
+	GrafanaProfile         string
+	PagerDutySecret        string
+	PagerDutyProfile       string
+	PagerDutyEscalation    string
+	GitHubSecret           string
+	GitHubRepos            []string
+	GitHubBranches         []string
+	GitHubWorkflows        []string
+	GitHubEnvironments     []string
+	GitHubFailureProfile   string
+	GitHubSuccessProfile   string
+	OpsgenieRoutes         []LabelRoute
+	OpsgenieProfile        string
+	GenericWebhooks        []GenericWebhook
+	MQTTBrokerURL          string
+	MQTTClientID           string
+	MQTTUsername           string
+	MQTTPassword           string
+	MQTTTopicPrefix        string
+	MQTTHealthIntervalSecs int
+	HADiscovery            bool
+	HADiscoveryPrefix      string
+	GRPCPort               int
+	GRPCListenAddress      string
+	APIKeys                []APIKey
+	PingRequiresAuth       bool
+	SignatureMaxAgeSecs    int
+	RateLimitPerMinute     int
+	RateLimitBurst         int
+	PageCooldownSecs       int
+	TLSCertFile            string
+	TLSKeyFile             string
+	TLSClientCAFile        string
+	TLSClientAuth          string
+	TLSClients             []TLSClient
+	ListenAddress          string
+	ListenSocketMode       uint32
+	ShutdownTimeoutSecs    int
+	CancelPagesOnShutdown  bool
+	HTTPCompatMode         bool
+	AuditLogFile           string
+	AuditLogMaxSizeMB      int
+	AuditLogMaxFiles       int
+}
+
+// GenericWebhook configures an inbound webhook whose payload is turned into
+// page and cancel commands through JSONPath expressions.
+type GenericWebhook struct {
+	Name         string
+	ActionPath   string
+	PageValues   []string
+	CancelValues []string
+	ProfilePath  string
+	Profile      string
+}
+
+// DefaultProfile is the name of the page profile built from the top level
+// color, duration and grouped light settings.
+const DefaultProfile = "default"
+
+// NoProfile, as the default profile of an integration, drops whatever
+// matches none of its routes instead of paging.
+const NoProfile = "none"
+
+// PageProfile describes a page: which grouped light to signal, the two
+// colors to alternate between and for how long.
+type PageProfile struct {
+	Name           string
+	GroupedLightID string
+	StartColorHex  string
+	JumpColorHex   string
+	StartColorXY   color.XY
+	JumpColorXY    color.XY
+	DurationMS     int
+}
+
+// LabelRoute maps a set of label matchers onto a page profile. A route
+// matches when every matcher is present with the same value.
+type LabelRoute struct {
+	Matchers map[string]string
+	Profile  string
+}
+
+// Matches reports whether every matcher of the route is satisfied by labels.
+func (r LabelRoute) Matches(labels map[string]string) bool {
+	for name, value := range r.Matchers {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrorCode tells callers what went wrong in a machine readable way.
+type ErrorCode string
+
+const (
+	ErrorConfigMissing            ErrorCode = "config_missing"
+	ErrorConfigInvalid            ErrorCode = "config_invalid"
+	ErrorBridgeUnreachable        ErrorCode = "bridge_unreachable"
+	ErrorBridgeTimeout            ErrorCode = "bridge_timeout"
+	ErrorBridgeRejected           ErrorCode = "bridge_rejected"
+	ErrorMarshalFailed            ErrorCode = "marshal_failed"
+	ErrorUnknownProfile           ErrorCode = "unknown_profile"
+	ErrorInvalidPayload           ErrorCode = "invalid_payload"
+	ErrorPayloadTooLarge          ErrorCode = "payload_too_large"
+	ErrorInvalidSignature         ErrorCode = "invalid_signature"
+	ErrorIntegrationNotConfigured ErrorCode = "integration_not_configured"
+	ErrorUnauthorized             ErrorCode = "unauthorized"
+	ErrorForbidden                ErrorCode = "forbidden"
+	ErrorMethodNotAllowed         ErrorCode = "method_not_allowed"
+	ErrorRateLimited              ErrorCode = "rate_limited"
+	ErrorCoalesced                ErrorCode = "coalesced"
+	ErrorInvalidQuery             ErrorCode = "invalid_query"
+	ErrorAuditFailed              ErrorCode = "audit_failed"
+)
+
+// HTTPStatus is the HTTP status code failures with this code answer with.
+func (c ErrorCode) HTTPStatus() int {
+	switch c {
+	case ErrorUnknownProfile, ErrorInvalidPayload, ErrorInvalidQuery:
+		return http.StatusBadRequest
+	case ErrorUnauthorized, ErrorInvalidSignature:
+		return http.StatusUnauthorized
+	case ErrorForbidden:
+		return http.StatusForbidden
+	case ErrorMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case ErrorPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case ErrorRateLimited, ErrorCoalesced:
+		return http.StatusTooManyRequests
+	case ErrorBridgeUnreachable, ErrorBridgeRejected:
+		return http.StatusBadGateway
+	case ErrorConfigMissing, ErrorConfigInvalid, ErrorIntegrationNotConfigured:
+		return http.StatusServiceUnavailable
+	case ErrorBridgeTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Retryable reports whether the same request may succeed if it is retried
+// later without changing anything.
+func (c ErrorCode) Retryable() bool {
+	switch c {
+	case ErrorBridgeUnreachable, ErrorBridgeTimeout, ErrorRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// ErrorDetails carries what the bridge said about a failed request.
+type ErrorDetails struct {
+	BridgeStatus int      `json:"bridge_status,omitempty"`
+	BridgeErrors []string `json:"bridge_errors,omitempty"`
 }
 
 // Response represents the structure of responses sent to clients.
 type Response struct {
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	Status    string        `json:"status"`
+	Code      ErrorCode     `json:"code,omitempty"`
+	Message   string        `json:"message,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Details   *ErrorDetails `json:"details,omitempty"`
+	// StatusCode is the HTTP status to answer with, where 0 means 200 for
+	// successes and 500 for errors.
+	StatusCode int `json:"-"`
+	// RetryAfter, if set, is sent as a Retry-After header.
+	RetryAfter time.Duration `json:"-"`
 }
 
 // Success creates a success response.
@@ -55,3 +390,9 @@ func Success() Response {
 func Error(message string) Response {
 	return Response{Status: "broke", Message: message}
 }
+
+// Failure creates an error response with a code and message, answered
+// with the code's HTTP status.
+func Failure(code ErrorCode, message string) Response {
+	return Response{Status: "broke", Code: code, Message: message, StatusCode: code.HTTPStatus()}
+}
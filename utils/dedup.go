@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+)
+
+// DedupNotifier wraps another Notifier and collapses identical error
+// events sent within a window into a single notification, followed by a
+// "repeated N times" summary when the window closes. Events that only
+// differ in their time and request ID count as identical. Events stay
+// active until Resolve is called, at which point a recovery is sent.
+type DedupNotifier struct {
+	Notifier types.Notifier
+	Window   time.Duration
+	Log      *logrus.Logger
+
+	mu     sync.Mutex
+	active map[types.NotificationEvent]*dedupEntry
+}
+
+type dedupEntry struct {
+	repeats int
+	timer   *time.Timer
+}
+
+// NewDedupNotifier creates a new DedupNotifier that forwards to the given Notifier.
+func NewDedupNotifier(notifier types.Notifier, window time.Duration, log *logrus.Logger) *DedupNotifier {
+	return &DedupNotifier{
+		Notifier: notifier,
+		Window:   window,
+		Log:      log,
+		active:   make(map[types.NotificationEvent]*dedupEntry),
+	}
+}
+
+func (d *DedupNotifier) SendErrorNotification(message string) error {
+	return d.SendNotification(types.LogLevelError, message)
+}
+
+// SendNotification forwards the message like SendEvent.
+func (d *DedupNotifier) SendNotification(level types.LogLevel, message string) error {
+	return d.SendEvent(types.MessageEvent(level, message))
+}
+
+// SendEvent forwards the event unless an identical error event was already
+// sent within the current window, in which case it is counted towards the
+// next summary.
+func (d *DedupNotifier) SendEvent(event types.NotificationEvent) error {
+	if event.Level != types.LogLevelError {
+		return types.SendEvent(d.Notifier, event)
+	}
+
+	key := event
+	key.Time = time.Time{}
+	key.RequestID = ""
+
+	d.mu.Lock()
+	entry, ok := d.active[key]
+	if ok && entry.timer != nil {
+		entry.repeats++
+		d.mu.Unlock()
+		return nil
+	}
+	if !ok {
+		entry = &dedupEntry{}
+		d.active[key] = entry
+	}
+	entry.timer = time.AfterFunc(d.Window, func() { d.flush(key) })
+	d.mu.Unlock()
+
+	return types.SendEvent(d.Notifier, event)
+}
+
+// Resolve sends any pending summaries and a recovery for every active
+// condition, then forgets about them.
+func (d *DedupNotifier) Resolve() error {
+	d.mu.Lock()
+	active := d.active
+	d.active = make(map[types.NotificationEvent]*dedupEntry)
+	d.mu.Unlock()
+
+	var firstErr error
+	for key, entry := range active {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		if entry.repeats > 0 {
+			if err := d.sendSummary(key, entry.repeats); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		recovery := key
+		recovery.Level = types.LogLevelInfo
+		recovery.Status = types.NotificationResolved
+		recovery.Message = "Recovered: " + key.Message
+		recovery.Time = time.Now()
+		if err := types.SendEvent(d.Notifier, recovery); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Flush sends the pending summaries of every active condition right away
+// instead of waiting for their windows to close, so they aren't lost on
+// shutdown.
+func (d *DedupNotifier) Flush() error {
+	d.mu.Lock()
+	pending := map[types.NotificationEvent]int{}
+	for key, entry := range d.active {
+		if entry.repeats > 0 {
+			pending[key] = entry.repeats
+			entry.repeats = 0
+		}
+	}
+	d.mu.Unlock()
+
+	var firstErr error
+	for key, repeats := range pending {
+		if err := d.sendSummary(key, repeats); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// flush runs at the end of a window. If the event repeated during the
+// window a summary is sent and a new window is started, otherwise the
+// entry goes idle until the event shows up again or it is resolved.
+func (d *DedupNotifier) flush(key types.NotificationEvent) {
+	d.mu.Lock()
+	entry, ok := d.active[key]
+	if !ok {
+		d.mu.Unlock()
+		return
+	}
+	repeats := entry.repeats
+	entry.repeats = 0
+	if repeats > 0 {
+		entry.timer = time.AfterFunc(d.Window, func() { d.flush(key) })
+	} else {
+		entry.timer = nil
+	}
+	d.mu.Unlock()
+
+	if repeats > 0 {
+		if err := d.sendSummary(key, repeats); err != nil {
+			d.Log.Error("Failed to send repeated notification summary: ", err)
+		}
+	}
+}
+
+func (d *DedupNotifier) sendSummary(key types.NotificationEvent, repeats int) error {
+	summary := key
+	summary.Message = fmt.Sprintf("%s (repeated %d times in the last %s)", key.Message, repeats, d.Window)
+	summary.Time = time.Now()
+	return types.SendEvent(d.Notifier, summary)
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type sentNotification struct {
+	level   types.LogLevel
+	message string
+}
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []sentNotification
+}
+
+func (r *recordingNotifier) SendErrorNotification(message string) error {
+	return r.SendNotification(types.LogLevelError, message)
+}
+
+func (r *recordingNotifier) SendNotification(level types.LogLevel, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, sentNotification{level: level, message: message})
+	return nil
+}
+
+func (r *recordingNotifier) messages() []sentNotification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]sentNotification(nil), r.sent...)
+}
+
+func TestDedupNotifier_CollapsesRepeats(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	inner := &recordingNotifier{}
+	notifier := NewDedupNotifier(inner, 50*time.Millisecond, log)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, notifier.SendErrorNotification("bridge down"))
+	}
+	assert.NoError(t, notifier.SendErrorNotification("something else"))
+
+	sent := inner.messages()
+	assert.Len(t, sent, 2)
+	assert.Equal(t, "bridge down", sent[0].message)
+	assert.Equal(t, "something else", sent[1].message)
+
+	assert.Eventually(t, func() bool {
+		return len(inner.messages()) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, inner.messages()[2].message, "bridge down (repeated 4 times")
+}
+
+func TestDedupNotifier_Resolve(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	inner := &recordingNotifier{}
+	notifier := NewDedupNotifier(inner, time.Minute, log)
+
+	assert.NoError(t, notifier.SendErrorNotification("bridge down"))
+	assert.NoError(t, notifier.SendErrorNotification("bridge down"))
+	assert.NoError(t, notifier.Resolve())
+
+	sent := inner.messages()
+	assert.Len(t, sent, 3)
+	assert.Contains(t, sent[1].message, "repeated 1 times")
+	assert.Equal(t, types.LogLevelInfo, sent[2].level)
+	assert.Equal(t, "Recovered: bridge down", sent[2].message)
+
+	assert.NoError(t, notifier.SendErrorNotification("bridge down"))
+	assert.Len(t, inner.messages(), 4)
+}
+
+func TestDedupNotifier_PassesThroughOtherLevels(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	inner := &recordingNotifier{}
+	notifier := NewDedupNotifier(inner, time.Minute, log)
+
+	assert.NoError(t, notifier.SendNotification(types.LogLevelWarn, "heads up"))
+	assert.NoError(t, notifier.SendNotification(types.LogLevelWarn, "heads up"))
+	assert.Len(t, inner.messages(), 2)
+}
+
+func TestDedupNotifier_Flush(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	inner := &recordingNotifier{}
+	notifier := NewDedupNotifier(inner, time.Hour, log)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, notifier.SendErrorNotification("bridge down"))
+	}
+	assert.NoError(t, notifier.Flush())
+	assert.NoError(t, notifier.Flush())
+
+	assert.Equal(t, []sentNotification{
+		{level: types.LogLevelError, message: "bridge down"},
+		{level: types.LogLevelError, message: "bridge down (repeated 2 times in the last 1h0m0s)"},
+	}, inner.messages())
+}
+
+func TestDedupNotifier_IgnoresRequestIDs(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	inner := &recordingNotifier{}
+	notifier := NewDedupNotifier(inner, time.Hour, log)
+
+	failed := func(requestID string) types.NotificationEvent {
+		return types.NotificationEvent{Level: types.LogLevelError, Message: "bridge down", Time: time.Now(), Handler: "PageHandler", Caller: "ci", Target: "office", Status: types.NotificationFiring, RequestID: requestID}
+	}
+	assert.NoError(t, notifier.SendEvent(failed("abc")))
+	assert.NoError(t, notifier.SendEvent(failed("def")))
+	assert.NoError(t, notifier.Resolve())
+
+	assert.Equal(t, []sentNotification{
+		{level: types.LogLevelError, message: "[PageHandler (ci)] bridge down (target office) (request abc)"},
+		{level: types.LogLevelError, message: "[PageHandler (ci)] bridge down (repeated 1 times in the last 1h0m0s) (target office)"},
+		{level: types.LogLevelInfo, message: "[PageHandler (ci)] Recovered: bridge down (target office)"},
+	}, inner.messages())
+}
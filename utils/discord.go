@@ -3,14 +3,53 @@ package utils
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/YashdalfTheGray/huproxy/types"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrDiscordRateLimited    = errors.New("discord rate limited the webhook")
+	ErrDiscordWebhookInvalid = errors.New("discord webhook is unauthorized or has been deleted")
+	ErrDiscordServerError    = errors.New("discord responded with a server error")
+	ErrDiscordUnexpected     = errors.New("discord responded with an unexpected status")
+)
+
+// discordClient gives up on Discord calls that hang, so a stuck webhook
+// can't block notifications or startup.
+var discordClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// discordFailures counts failed Discord deliveries by kind, published on
+// /debug/vars.
+var discordFailures = expvar.NewMap("discord_notification_failures")
+
+// DiscordError is returned when Discord responds to a webhook call with a
+// non-2xx status. It unwraps to one of the ErrDiscord sentinel errors.
+type DiscordError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *DiscordError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s (status code %d, retry after %s)", e.Err, e.StatusCode, e.RetryAfter)
+	}
+	return fmt.Sprintf("%s (status code %d)", e.Err, e.StatusCode)
+}
+
+func (e *DiscordError) Unwrap() error {
+	return e.Err
+}
+
 type DiscordNotifier struct {
 	Config *types.Config
 	Log    *logrus.Logger
@@ -28,7 +67,12 @@ func (d *DiscordNotifier) SendErrorNotification(message string) error {
 	return d.sendNotification(d.Config.ErrorDiscordWebhookUrl, message, types.LogLevelError)
 }
 
-// SendNotification sends a message to the configured Discord webhook URL.
+// SendNotification sends a message at the given level to the error webhook.
+func (d *DiscordNotifier) SendNotification(level types.LogLevel, message string) error {
+	return d.sendNotification(d.Config.ErrorDiscordWebhookUrl, message, level)
+}
+
+// sendNotification sends a message to the given Discord webhook URL.
 func (d *DiscordNotifier) sendNotification(webhookURL string, message string, level types.LogLevel) error {
 	if webhookURL == "" {
 		d.Log.Warn("No Discord webhook URL provided, skipping notification")
@@ -51,17 +95,65 @@ func (d *DiscordNotifier) sendNotification(webhookURL string, message string, le
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := discordClient.Do(req)
 	if err != nil {
 		d.Log.Error("Failed to send HTTP request: ", err)
+		discordFailures.Add("transport", 1)
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		d.Log.Warnf("Failed to send notification, status code: %d", resp.StatusCode)
+		err := newDiscordError(resp)
+		d.Log.Warn("Failed to send notification: ", err)
+		return err
 	}
 
 	return nil
 }
+
+// Validate checks that the error webhook exists and is usable by fetching
+// it, which Discord allows without sending a message.
+func (d *DiscordNotifier) Validate() error {
+	if d.Config.ErrorDiscordWebhookUrl == "" {
+		return nil
+	}
+
+	resp, err := discordClient.Get(d.Config.ErrorDiscordWebhookUrl)
+	if err != nil {
+		discordFailures.Add("transport", 1)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newDiscordError(resp)
+	}
+
+	return nil
+}
+
+// newDiscordError classifies a failed Discord response and counts it.
+func newDiscordError(resp *http.Response) *DiscordError {
+	discordErr := &DiscordError{StatusCode: resp.StatusCode}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		discordErr.Err = ErrDiscordRateLimited
+		if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+			discordErr.RetryAfter = time.Duration(seconds * float64(time.Second))
+		}
+		discordFailures.Add("rate_limited", 1)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound:
+		discordErr.Err = ErrDiscordWebhookInvalid
+		discordFailures.Add("webhook_invalid", 1)
+	case resp.StatusCode >= 500:
+		discordErr.Err = ErrDiscordServerError
+		discordFailures.Add("server_error", 1)
+	default:
+		discordErr.Err = ErrDiscordUnexpected
+		discordFailures.Add("unexpected_status", 1)
+	}
+
+	return discordErr
+}
@@ -46,3 +46,82 @@ func TestDiscordNotifier_SendErrorNotification(t *testing.T) {
 	err := notifier.SendErrorNotification("test message")
 	assert.NoError(t, err)
 }
+
+func TestDiscordNotifier_ErrorStatuses(t *testing.T) {
+	tests := []struct {
+		description string
+		statusCode  int
+		retryAfter  string
+		expectedErr error
+	}{
+		{"rate limited", http.StatusTooManyRequests, "1.5", ErrDiscordRateLimited},
+		{"unauthorized", http.StatusUnauthorized, "", ErrDiscordWebhookInvalid},
+		{"deleted webhook", http.StatusNotFound, "", ErrDiscordWebhookInvalid},
+		{"server error", http.StatusBadGateway, "", ErrDiscordServerError},
+		{"bad request", http.StatusBadRequest, "", ErrDiscordUnexpected},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			log := logrus.New()
+			log.SetOutput(bytes.NewBuffer(nil))
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if test.retryAfter != "" {
+					w.Header().Set("Retry-After", test.retryAfter)
+				}
+				w.WriteHeader(test.statusCode)
+			}))
+			defer server.Close()
+
+			notifier := NewDiscordNotifier(&types.Config{ErrorDiscordWebhookUrl: server.URL}, log)
+
+			err := notifier.SendErrorNotification("test message")
+			assert.ErrorIs(t, err, test.expectedErr)
+
+			var discordErr *DiscordError
+			assert.ErrorAs(t, err, &discordErr)
+			assert.Equal(t, test.statusCode, discordErr.StatusCode)
+			if test.retryAfter != "" {
+				assert.Equal(t, 1500*time.Millisecond, discordErr.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestDiscordNotifier_Validate(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	valid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer valid.Close()
+
+	deleted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer deleted.Close()
+
+	assert.NoError(t, NewDiscordNotifier(&types.Config{ErrorDiscordWebhookUrl: valid.URL}, log).Validate())
+	assert.ErrorIs(t, NewDiscordNotifier(&types.Config{ErrorDiscordWebhookUrl: deleted.URL}, log).Validate(), ErrDiscordWebhookInvalid)
+}
+
+func TestDiscordNotifier_ValidateTimesOut(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer server.Close()
+	defer close(hung)
+
+	defer func(client *http.Client) { discordClient = client }(discordClient)
+	discordClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+	err := NewDiscordNotifier(&types.Config{ErrorDiscordWebhookUrl: server.URL}, log).Validate()
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+)
+
+type EmailNotifier struct {
+	Config *types.Config
+	Log    *logrus.Logger
+	// TLSConfig is used for STARTTLS and implicit TLS connections. If nil, a
+	// config verifying the server against SMTPHost is used.
+	TLSConfig *tls.Config
+	// Timeout bounds connecting and the whole SMTP conversation after
+	// that, so a server that stops answering can't hold up the page that
+	// sent the notification. If zero, 10 seconds is used.
+	Timeout time.Duration
+}
+
+// NewEmailNotifier creates a new EmailNotifier with the given Config and Logger.
+func NewEmailNotifier(config *types.Config, log *logrus.Logger) *EmailNotifier {
+	return &EmailNotifier{
+		Config: config,
+		Log:    log,
+	}
+}
+
+func (e *EmailNotifier) SendErrorNotification(message string) error {
+	return e.SendNotification(types.LogLevelError, message)
+}
+
+// SendNotification emails the message to every configured recipient.
+func (e *EmailNotifier) SendNotification(level types.LogLevel, message string) error {
+	return e.SendEvent(types.MessageEvent(level, message))
+}
+
+// SendEvent emails the event to every configured recipient.
+func (e *EmailNotifier) SendEvent(event types.NotificationEvent) error {
+	if e.Config.SMTPHost == "" || e.Config.SMTPFrom == "" || len(e.Config.SMTPTo) == 0 {
+		e.Log.Warn("SMTP is not fully configured, skipping notification")
+		return nil
+	}
+
+	client, err := e.dial()
+	if err != nil {
+		e.Log.Error("Failed to connect to SMTP server: ", err)
+		return err
+	}
+	defer client.Close()
+
+	if e.Config.SMTPTLSMode == "starttls" {
+		if err := client.StartTLS(e.tlsConfig()); err != nil {
+			e.Log.Error("Failed to start TLS with SMTP server: ", err)
+			return err
+		}
+	}
+
+	if e.Config.SMTPUsername != "" {
+		auth := smtp.PlainAuth("", e.Config.SMTPUsername, e.Config.SMTPPassword, e.Config.SMTPHost)
+		if err := client.Auth(auth); err != nil {
+			e.Log.Error("Failed to authenticate with SMTP server: ", err)
+			return err
+		}
+	}
+
+	if err := client.Mail(e.Config.SMTPFrom); err != nil {
+		return err
+	}
+	for _, recipient := range e.Config.SMTPTo {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(e.buildMessage(event)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the SMTP server, wrapping the connection in TLS straight
+// away when implicit TLS is configured.
+func (e *EmailNotifier) dial() (*smtp.Client, error) {
+	address := net.JoinHostPort(e.Config.SMTPHost, strconv.Itoa(e.Config.SMTPPort))
+	timeout := e.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if e.Config.SMTPTLSMode == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, e.tlsConfig())
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return smtp.NewClient(conn, e.Config.SMTPHost)
+}
+
+func (e *EmailNotifier) tlsConfig() *tls.Config {
+	if e.TLSConfig != nil {
+		return e.TLSConfig
+	}
+	return &tls.Config{ServerName: e.Config.SMTPHost}
+}
+
+// buildMessage renders the RFC 5322 message, deriving the subject from the
+// level and the handler that raised the notification.
+func (e *EmailNotifier) buildMessage(event types.NotificationEvent) []byte {
+	subject := fmt.Sprintf("[huproxy] %s", event.Level.String())
+	if event.Handler != "" {
+		subject = fmt.Sprintf("%s in %s", subject, event.Handler)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.Config.SMTPFrom)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.Config.SMTPTo, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(event.String())
+	b.WriteString("\r\n")
+
+	return []byte(b.String())
+}
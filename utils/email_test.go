@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer is just enough of an SMTP server to accept a single
+// message, optionally over implicit TLS or STARTTLS.
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu         sync.Mutex
+	auth       string
+	from       string
+	recipients []string
+	data       string
+	startedTLS bool
+	done       chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T, tlsConfig *tls.Config, implicitTLS bool) *fakeSMTPServer {
+	var listener net.Listener
+	var err error
+	if implicitTLS {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := &fakeSMTPServer{listener: listener, tlsConfig: tlsConfig, done: make(chan struct{})}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	write("220 fake ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO", "HELO":
+			write("250-fake")
+			if s.tlsConfig != nil && !s.startedTLS {
+				write("250-STARTTLS")
+			}
+			write("250 AUTH PLAIN")
+		case "STARTTLS":
+			write("220 go ahead")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			s.mu.Lock()
+			s.startedTLS = true
+			s.mu.Unlock()
+		case "AUTH":
+			s.mu.Lock()
+			s.auth = line
+			s.mu.Unlock()
+			write("235 authenticated")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = line
+			s.mu.Unlock()
+			write("250 ok")
+		case "RCPT":
+			s.mu.Lock()
+			s.recipients = append(s.recipients, line)
+			s.mu.Unlock()
+			write("250 ok")
+		case "DATA":
+			write("354 send it")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			write("250 queued")
+		case "QUIT":
+			write("221 bye")
+			return
+		default:
+			write("250 ok")
+		}
+	}
+}
+
+func testTLSConfigs() (*tls.Config, *tls.Config) {
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	return &tls.Config{Certificates: ts.TLS.Certificates}, &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+func TestEmailNotifier_SendNotification(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	server := newFakeSMTPServer(t, nil, false)
+
+	cfg := &types.Config{
+		SMTPHost:     "127.0.0.1",
+		SMTPPort:     server.port(),
+		SMTPUsername: "huproxy",
+		SMTPPassword: "hunter2",
+		SMTPFrom:     "huproxy@example.com",
+		SMTPTo:       []string{"one@example.com", "two@example.com"},
+		SMTPTLSMode:  "none",
+	}
+	notifier := NewEmailNotifier(cfg, log)
+
+	err := notifier.SendEvent(types.NotificationEvent{Level: types.LogLevelError, Message: "Error sending Hue API the request.", Time: time.Now(), Handler: "PageHandler"})
+	assert.NoError(t, err)
+	<-server.done
+
+	assert.True(t, strings.HasPrefix(server.auth, "AUTH PLAIN"))
+	assert.Contains(t, server.from, "huproxy@example.com")
+	assert.Len(t, server.recipients, 2)
+	assert.Contains(t, server.data, "Subject: [huproxy] ERROR in PageHandler\r\n")
+	assert.Contains(t, server.data, "To: one@example.com, two@example.com\r\n")
+	assert.Contains(t, server.data, "Error sending Hue API the request.")
+}
+
+func TestEmailNotifier_TLSModes(t *testing.T) {
+	serverTLS, clientTLS := testTLSConfigs()
+
+	for _, mode := range []string{"starttls", "tls"} {
+		t.Run(mode, func(t *testing.T) {
+			log := logrus.New()
+			log.SetOutput(bytes.NewBuffer(nil))
+
+			server := newFakeSMTPServer(t, serverTLS, mode == "tls")
+
+			cfg := &types.Config{
+				SMTPHost:    "127.0.0.1",
+				SMTPPort:    server.port(),
+				SMTPFrom:    "huproxy@example.com",
+				SMTPTo:      []string{"one@example.com"},
+				SMTPTLSMode: mode,
+			}
+			notifier := NewEmailNotifier(cfg, log)
+			notifier.TLSConfig = clientTLS
+
+			err := notifier.SendNotification(types.LogLevelWarn, "something happened")
+			assert.NoError(t, err)
+			<-server.done
+
+			assert.Equal(t, mode == "starttls", server.startedTLS)
+			assert.Contains(t, server.data, "Subject: [huproxy] WARN\r\n")
+		})
+	}
+}
+
+func TestEmailNotifier_SkipsWhenUnconfigured(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	notifier := NewEmailNotifier(&types.Config{SMTPHost: "127.0.0.1", SMTPPort: 1}, log)
+	assert.NoError(t, notifier.SendErrorNotification("test message"))
+}
+
+func TestEmailNotifier_TimesOut(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	// a server that accepts the connection but never sends its greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	address := listener.Addr().(*net.TCPAddr)
+	cfg := &types.Config{SMTPHost: "127.0.0.1", SMTPPort: address.Port, SMTPFrom: "huproxy@example.com", SMTPTo: []string{"ops@example.com"}}
+	notifier := NewEmailNotifier(cfg, log)
+	notifier.Timeout = 50 * time.Millisecond
+
+	err = notifier.SendErrorNotification("test message")
+	var netErr net.Error
+	assert.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+)
+
+type GotifyNotifier struct {
+	Config *types.Config
+	Log    *logrus.Logger
+	Client *http.Client
+}
+
+// NewGotifyNotifier creates a new GotifyNotifier with the given Config and Logger.
+func NewGotifyNotifier(config *types.Config, log *logrus.Logger) *GotifyNotifier {
+	return &GotifyNotifier{
+		Config: config,
+		Log:    log,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (g *GotifyNotifier) SendErrorNotification(message string) error {
+	return g.SendNotification(types.LogLevelError, message)
+}
+
+// SendNotification posts a message to the configured Gotify server.
+func (g *GotifyNotifier) SendNotification(level types.LogLevel, message string) error {
+	if g.Config.GotifyURL == "" {
+		g.Log.Warn("No Gotify URL provided, skipping notification")
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"title":    fmt.Sprintf("huproxy %s", level.String()),
+		"message":  message,
+		"priority": g.priority(level),
+	}
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		g.Log.Error("Failed to marshal JSON payload: ", err)
+		return err
+	}
+
+	url := strings.TrimSuffix(g.Config.GotifyURL, "/") + "/message"
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		g.Log.Error("Failed to create new HTTP request: ", err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.Config.GotifyAppToken)
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		g.Log.Error("Failed to send HTTP request: ", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("gotify responded with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// priority maps a log level onto Gotify's 0 to 10 scale unless a fixed
+// priority is configured. Gotify clients only make noise from 4 upwards.
+func (g *GotifyNotifier) priority(level types.LogLevel) int {
+	if g.Config.GotifyPriority != 0 {
+		return g.Config.GotifyPriority
+	}
+
+	switch level {
+	case types.LogLevelInfo:
+		return 2
+	case types.LogLevelWarn:
+		return 5
+	default:
+		return 8
+	}
+}
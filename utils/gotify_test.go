@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGotifyNotifier_SendNotification(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/message", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "app-token", r.Header.Get("X-Gotify-Key"))
+
+		var payload map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		assert.NoError(t, err)
+		assert.Equal(t, "huproxy WARN", payload["title"])
+		assert.Equal(t, "test message", payload["message"])
+		assert.Equal(t, float64(5), payload["priority"])
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{
+		GotifyURL:      server.URL + "/",
+		GotifyAppToken: "app-token",
+	}
+	notifier := NewGotifyNotifier(cfg, log)
+
+	err := notifier.SendNotification(types.LogLevelWarn, "test message")
+	assert.NoError(t, err)
+}
+
+func TestGotifyNotifier_Priority(t *testing.T) {
+	notifier := NewGotifyNotifier(&types.Config{}, logrus.New())
+	assert.Equal(t, 2, notifier.priority(types.LogLevelInfo))
+	assert.Equal(t, 5, notifier.priority(types.LogLevelWarn))
+	assert.Equal(t, 8, notifier.priority(types.LogLevelError))
+
+	notifier.Config.GotifyPriority = 10
+	assert.Equal(t, 10, notifier.priority(types.LogLevelInfo))
+}
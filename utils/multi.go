@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"errors"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+)
+
+// MultiNotifier fans every notification out to all of its Notifiers.
+type MultiNotifier []types.Notifier
+
+func (m MultiNotifier) SendErrorNotification(message string) error {
+	return m.SendNotification(types.LogLevelError, message)
+}
+
+// SendNotification sends the message to every Notifier, returning the
+// joined errors of those that failed.
+func (m MultiNotifier) SendNotification(level types.LogLevel, message string) error {
+	return m.SendEvent(types.MessageEvent(level, message))
+}
+
+// SendEvent sends the event to every Notifier, as text to those that don't
+// take events, returning the joined errors of those that failed.
+func (m MultiNotifier) SendEvent(event types.NotificationEvent) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := types.SendEvent(notifier, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
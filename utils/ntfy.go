@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+)
+
+type NtfyNotifier struct {
+	Config *types.Config
+	Log    *logrus.Logger
+	Client *http.Client
+}
+
+// NewNtfyNotifier creates a new NtfyNotifier with the given Config and Logger.
+func NewNtfyNotifier(config *types.Config, log *logrus.Logger) *NtfyNotifier {
+	return &NtfyNotifier{
+		Config: config,
+		Log:    log,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *NtfyNotifier) SendErrorNotification(message string) error {
+	return n.SendNotification(types.LogLevelError, message)
+}
+
+// SendNotification publishes a message to the configured ntfy topic URL.
+func (n *NtfyNotifier) SendNotification(level types.LogLevel, message string) error {
+	if n.Config.NtfyTopicURL == "" {
+		n.Log.Warn("No ntfy topic URL provided, skipping notification")
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", n.Config.NtfyTopicURL, strings.NewReader(message))
+	if err != nil {
+		n.Log.Error("Failed to create new HTTP request: ", err)
+		return err
+	}
+	req.Header.Set("Title", fmt.Sprintf("huproxy %s", level.String()))
+	req.Header.Set("Priority", strconv.Itoa(n.priority(level)))
+	if len(n.Config.NtfyTags) > 0 {
+		req.Header.Set("Tags", strings.Join(n.Config.NtfyTags, ","))
+	}
+	if n.Config.NtfyToken != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Config.NtfyToken)
+	}
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		n.Log.Error("Failed to send HTTP request: ", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("ntfy responded with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// priority maps a log level onto ntfy's 1 (min) to 5 (max) scale unless a
+// fixed priority is configured.
+func (n *NtfyNotifier) priority(level types.LogLevel) int {
+	if n.Config.NtfyPriority != 0 {
+		return n.Config.NtfyPriority
+	}
+
+	switch level {
+	case types.LogLevelInfo:
+		return 3
+	case types.LogLevelWarn:
+		return 4
+	default:
+		return 5
+	}
+}
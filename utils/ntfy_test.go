@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNtfyNotifier_SendNotification(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/huproxy", r.URL.Path)
+		assert.Equal(t, "huproxy ERROR", r.Header.Get("Title"))
+		assert.Equal(t, "5", r.Header.Get("Priority"))
+		assert.Equal(t, "rotating_light,hue", r.Header.Get("Tags"))
+		assert.Equal(t, "Bearer tk_secret", r.Header.Get("Authorization"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "test message", string(body))
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{
+		NtfyTopicURL: server.URL + "/huproxy",
+		NtfyTags:     []string{"rotating_light", "hue"},
+		NtfyToken:    "tk_secret",
+	}
+	notifier := NewNtfyNotifier(cfg, log)
+
+	err := notifier.SendErrorNotification("test message")
+	assert.NoError(t, err)
+}
+
+func TestNtfyNotifier_Priority(t *testing.T) {
+	notifier := NewNtfyNotifier(&types.Config{}, logrus.New())
+	assert.Equal(t, 3, notifier.priority(types.LogLevelInfo))
+	assert.Equal(t, 4, notifier.priority(types.LogLevelWarn))
+	assert.Equal(t, 5, notifier.priority(types.LogLevelError))
+
+	notifier.Config.NtfyPriority = 2
+	assert.Equal(t, 2, notifier.priority(types.LogLevelError))
+}
+
+func TestNtfyNotifier_ErrorStatus(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	notifier := NewNtfyNotifier(&types.Config{NtfyTopicURL: server.URL}, log)
+	assert.Error(t, notifier.SendErrorNotification("test message"))
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+)
+
+// webhookTemplateFuncs are available to every webhook template on top of
+// the text/template builtins.
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+type webhookHeader struct {
+	name  string
+	value *template.Template
+}
+
+// WebhookNotifier sends notifications to an arbitrary HTTP endpoint. The
+// URL, method, headers and body are text/template templates rendered
+// against a types.NotificationEvent.
+type WebhookNotifier struct {
+	Config *types.Config
+	Log    *logrus.Logger
+	Client *http.Client
+
+	url     *template.Template
+	method  *template.Template
+	headers []webhookHeader
+	body    *template.Template
+}
+
+// NewWebhookNotifier creates a new WebhookNotifier with the given Config and
+// Logger, returning an error if any of the templates fail to parse.
+func NewWebhookNotifier(config *types.Config, log *logrus.Logger) (*WebhookNotifier, error) {
+	w := &WebhookNotifier{
+		Config: config,
+		Log:    log,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	var err error
+	if w.url, err = parseWebhookTemplate("url", config.WebhookURL); err != nil {
+		return nil, err
+	}
+	if w.method, err = parseWebhookTemplate("method", config.WebhookMethod); err != nil {
+		return nil, err
+	}
+	if w.body, err = parseWebhookTemplate("body", config.WebhookBody); err != nil {
+		return nil, err
+	}
+	for _, header := range config.WebhookHeaders {
+		name, value, _ := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		tmpl, err := parseWebhookTemplate("header "+name, strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		w.headers = append(w.headers, webhookHeader{name: name, value: tmpl})
+	}
+
+	return w, nil
+}
+
+func (w *WebhookNotifier) SendErrorNotification(message string) error {
+	return w.SendNotification(types.LogLevelError, message)
+}
+
+// SendNotification sends the message like SendEvent.
+func (w *WebhookNotifier) SendNotification(level types.LogLevel, message string) error {
+	return w.SendEvent(types.MessageEvent(level, message))
+}
+
+// SendEvent renders the templates against the event and sends the
+// resulting request. Events that aren't about a particular grouped light
+// get GROUPED_LIGHT_ID as their target.
+func (w *WebhookNotifier) SendEvent(event types.NotificationEvent) error {
+	if event.Target == "" {
+		event.Target = w.Config.GroupedLightID
+	}
+
+	url, err := renderWebhookTemplate(w.url, event)
+	if err != nil {
+		w.Log.Error("Failed to render webhook URL: ", err)
+		return err
+	}
+	if url == "" {
+		w.Log.Warn("No webhook URL provided, skipping notification")
+		return nil
+	}
+
+	method, err := renderWebhookTemplate(w.method, event)
+	if err != nil {
+		w.Log.Error("Failed to render webhook method: ", err)
+		return err
+	}
+
+	body, err := renderWebhookTemplate(w.body, event)
+	if err != nil {
+		w.Log.Error("Failed to render webhook body: ", err)
+		return err
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(strings.TrimSpace(method)), strings.TrimSpace(url), strings.NewReader(body))
+	if err != nil {
+		w.Log.Error("Failed to create new HTTP request: ", err)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, header := range w.headers {
+		value, err := renderWebhookTemplate(header.value, event)
+		if err != nil {
+			w.Log.Error("Failed to render webhook header: ", err)
+			return err
+		}
+		req.Header.Set(header.name, value)
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		w.Log.Error("Failed to send HTTP request: ", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func parseWebhookTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(webhookTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func renderWebhookTemplate(tmpl *template.Template, event types.NotificationEvent) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/YashdalfTheGray/huproxy/config"
+	"github.com/YashdalfTheGray/huproxy/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookNotifier_DefaultBody(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var payload map[string]string
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		assert.NoError(t, err)
+		assert.Equal(t, "ERROR", payload["level"])
+		assert.Equal(t, "Error sending Hue API the request.", payload["message"])
+		assert.Equal(t, "PageHandler", payload["handler"])
+		assert.Equal(t, "ci", payload["caller"])
+		assert.Equal(t, "group1", payload["target"])
+		assert.Equal(t, "firing", payload["status"])
+		assert.NotEmpty(t, payload["time"])
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{
+		GroupedLightID: "group1",
+		WebhookURL:     server.URL,
+		WebhookMethod:  "POST",
+		WebhookBody:    config.DefaultWebhookBody,
+	}
+	notifier, err := NewWebhookNotifier(cfg, log)
+	assert.NoError(t, err)
+
+	err = notifier.SendEvent(types.NotificationEvent{
+		Level:   types.LogLevelError,
+		Message: "Error sending Hue API the request.",
+		Time:    time.Now(),
+		Handler: "PageHandler",
+		Caller:  "ci",
+		Status:  types.NotificationFiring,
+	})
+	assert.NoError(t, err)
+}
+
+func TestWebhookNotifier_Target(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{
+		GroupedLightID: "group1",
+		WebhookURL:     server.URL,
+		WebhookMethod:  "POST",
+		WebhookBody:    `{{.Handler}}|{{.Target}}|{{.Status}}|{{.Message}}|{{.RequestID}}`,
+	}
+	notifier, err := NewWebhookNotifier(cfg, log)
+	assert.NoError(t, err)
+	dedup := NewDedupNotifier(notifier, time.Hour, log)
+
+	failed := func(requestID string) types.NotificationEvent {
+		return types.NotificationEvent{Level: types.LogLevelError, Message: "bridge down", Time: time.Now(), Handler: "PageHandler", Target: "office", Status: types.NotificationFiring, RequestID: requestID}
+	}
+	assert.NoError(t, dedup.SendEvent(failed("abc")))
+	assert.NoError(t, dedup.SendEvent(failed("def")))
+	assert.NoError(t, dedup.Flush())
+	assert.NoError(t, dedup.Resolve())
+	assert.NoError(t, notifier.SendEvent(types.NotificationEvent{Level: types.LogLevelError, Message: "Missing one or more environment variables.", Handler: "PingHandler", Status: types.NotificationFiring}))
+
+	assert.Equal(t, []string{
+		"PageHandler|office|firing|bridge down|abc",
+		"PageHandler|office|firing|bridge down (repeated 1 times in the last 1h0m0s)|",
+		"PageHandler|office|resolved|Recovered: bridge down|",
+		"PingHandler|group1|firing|Missing one or more environment variables.|",
+	}, bodies)
+}
+
+func TestWebhookNotifier_CustomTemplates(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(bytes.NewBuffer(nil))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(t, "/rooms/resolved", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "info", r.Header.Get("X-Level"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"text":"INFO: Recovered"}`, string(body))
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &types.Config{
+		WebhookURL:     server.URL + "/rooms/{{.Status}}",
+		WebhookMethod:  `{{if eq .Status "resolved"}}put{{else}}post{{end}}`,
+		WebhookHeaders: []string{"Authorization: Bearer secret", "X-Level: {{lower .Level.String}}"},
+		WebhookBody:    `{"text":"{{.Level}}: {{.Message}}"}`,
+	}
+	notifier, err := NewWebhookNotifier(cfg, log)
+	assert.NoError(t, err)
+
+	err = notifier.SendEvent(types.NotificationEvent{Level: types.LogLevelInfo, Message: "Recovered", Status: types.NotificationResolved})
+	assert.NoError(t, err)
+}
+
+func TestWebhookNotifier_InvalidTemplate(t *testing.T) {
+	cfg := &types.Config{
+		WebhookURL:    "http://localhost",
+		WebhookMethod: "POST",
+		WebhookBody:   "{{.Nope",
+	}
+	_, err := NewWebhookNotifier(cfg, logrus.New())
+	assert.Error(t, err)
+}